@@ -0,0 +1,271 @@
+package clickhouse
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GetArray returns elements of Array value converted by parse
+// Quoted elements are passed to parse unquoted and unescaped, NULL elements are passed as \N
+// Both array literals (TabSeparated and CSV formats) and JSON arrays (JSON formats) are supported
+func GetArray[T any](result Result, column string, parse func(string) (T, error)) ([]T, error) {
+	value, err := result.raw(column)
+	if err != nil {
+		return nil, err
+	}
+
+	elements, err := arrayElements(value)
+	if err != nil {
+		return nil, arrayError(value, err)
+	}
+
+	array := make([]T, 0, len(elements))
+	for _, element := range elements {
+		item, err := parse(element)
+		if err != nil {
+			return nil, arrayError(value, err)
+		}
+
+		array = append(array, item)
+	}
+
+	return array, nil
+}
+
+// GetNullableArray returns elements of Array(Nullable(T)) value converted by parse with nil for NULL elements
+// Quoted elements are passed to parse unquoted and unescaped so string 'null' isn't NULL
+// Both array literals (TabSeparated and CSV formats) and JSON arrays (JSON formats) are supported
+func GetNullableArray[T any](result Result, column string, parse func(string) (T, error)) ([]*T, error) {
+	value, err := result.raw(column)
+	if err != nil {
+		return nil, err
+	}
+
+	elements, err := nullableElements(value)
+	if err != nil {
+		return nil, arrayError(value, err)
+	}
+
+	array := make([]*T, 0, len(elements))
+	for _, element := range elements {
+		if element == nil {
+			array = append(array, nil)
+
+			continue
+		}
+
+		item, err := parse(*element)
+		if err != nil {
+			return nil, arrayError(value, err)
+		}
+
+		array = append(array, &item)
+	}
+
+	return array, nil
+}
+
+// GetMap returns Map value with keys and values converted by parseKey and parseValue
+// Quoted keys and values are passed to parsers unquoted and unescaped, NULL values are passed as \N
+// Both map literals (TabSeparated and CSV formats) and JSON objects (JSON formats) are supported
+func GetMap[K comparable, V any](result Result, column string, parseKey func(string) (K, error), parseValue func(string) (V, error)) (map[K]V, error) {
+	value, err := result.raw(column)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs, err := mapPairs(value)
+	if err != nil {
+		return nil, mapError(value, err)
+	}
+
+	m := make(map[K]V, len(pairs))
+	for rawKey, rawValue := range pairs {
+		key, err := parseKey(rawKey)
+		if err != nil {
+			return nil, mapError(value, err)
+		}
+
+		m[key], err = parseValue(rawValue)
+		if err != nil {
+			return nil, mapError(value, err)
+		}
+	}
+
+	return m, nil
+}
+
+// arrayElements returns text of elements of array literal or JSON array with \N for NULL elements
+func arrayElements(value string) ([]string, error) {
+	nullable, err := nullableElements(value)
+	if err != nil {
+		return nil, err
+	}
+
+	elements := make([]string, 0, len(nullable))
+	for _, element := range nullable {
+		if element == nil {
+			elements = append(elements, `\N`)
+		} else {
+			elements = append(elements, *element)
+		}
+	}
+
+	return elements, nil
+}
+
+// nullableElements returns text of elements of array literal or JSON array with nil for NULL elements
+// Only bare NULL (or null of JSON) is NULL while quoted strings are elements even if they are 'null' or '\N'
+func nullableElements(value string) ([]*string, error) {
+	var raws []json.RawMessage
+	if json.Unmarshal([]byte(value), &raws) == nil {
+		elements := make([]*string, 0, len(raws))
+		for _, raw := range raws {
+			if string(raw) == "null" {
+				elements = append(elements, nil)
+
+				continue
+			}
+
+			text := jsonText(raw)
+			elements = append(elements, &text)
+		}
+
+		return elements, nil
+	}
+
+	literals, err := splitLiteral(value, '[', ']')
+	if err != nil {
+		return nil, err
+	}
+
+	elements := make([]*string, 0, len(literals))
+	for _, literal := range literals {
+		if strings.EqualFold(literal, "NULL") {
+			elements = append(elements, nil)
+
+			continue
+		}
+
+		text := literalText(literal)
+		elements = append(elements, &text)
+	}
+
+	return elements, nil
+}
+
+// mapPairs returns text of keys and values of map literal or JSON object
+func mapPairs(value string) (map[string]string, error) {
+	var raws map[string]json.RawMessage
+	if json.Unmarshal([]byte(value), &raws) == nil {
+		pairs := make(map[string]string, len(raws))
+		for key, raw := range raws {
+			pairs[key] = jsonText(raw)
+		}
+
+		return pairs, nil
+	}
+
+	literals, err := splitLiteral(value, '{', '}')
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make(map[string]string, len(literals))
+	for _, literal := range literals {
+		key, value, err := splitPair(literal)
+		if err != nil {
+			return nil, err
+		}
+
+		pairs[literalText(key)] = literalText(value)
+	}
+
+	return pairs, nil
+}
+
+// ArrayString returns value of Array(String)
+func (result Result) ArrayString(column string) ([]string, error) {
+	return GetArray(result, column, func(element string) (string, error) {
+		return element, nil
+	})
+}
+
+// ArrayInt64 returns value of array of signed integers
+func (result Result) ArrayInt64(column string) ([]int64, error) {
+	return GetArray(result, column, func(element string) (int64, error) {
+		return strconv.ParseInt(element, 10, 64)
+	})
+}
+
+// ArrayUInt64 returns value of array of unsigned integers
+func (result Result) ArrayUInt64(column string) ([]uint64, error) {
+	return GetArray(result, column, func(element string) (uint64, error) {
+		return strconv.ParseUint(element, 10, 64)
+	})
+}
+
+// ArrayFloat64 returns value of array of floats
+func (result Result) ArrayFloat64(column string) ([]float64, error) {
+	return GetArray(result, column, func(element string) (float64, error) {
+		return strconv.ParseFloat(element, 64)
+	})
+}
+
+// ArrayBool returns value of Array(Bool) (true and false or 1 and 0 elements)
+func (result Result) ArrayBool(column string) ([]bool, error) {
+	return GetArray(result, column, strconv.ParseBool)
+}
+
+// ArrayNullableString returns value of Array(Nullable(String)) with nil for NULL elements
+func (result Result) ArrayNullableString(column string) ([]*string, error) {
+	return GetNullableArray(result, column, func(element string) (string, error) {
+		return element, nil
+	})
+}
+
+// ArrayNullableInt64 returns value of array of nullable signed integers with nil for NULL elements
+func (result Result) ArrayNullableInt64(column string) ([]*int64, error) {
+	return GetNullableArray(result, column, func(element string) (int64, error) {
+		return strconv.ParseInt(element, 10, 64)
+	})
+}
+
+// ArrayNullableUInt64 returns value of array of nullable unsigned integers with nil for NULL elements
+func (result Result) ArrayNullableUInt64(column string) ([]*uint64, error) {
+	return GetNullableArray(result, column, func(element string) (uint64, error) {
+		return strconv.ParseUint(element, 10, 64)
+	})
+}
+
+// ArrayNullableFloat64 returns value of array of nullable floats with nil for NULL elements
+func (result Result) ArrayNullableFloat64(column string) ([]*float64, error) {
+	return GetNullableArray(result, column, func(element string) (float64, error) {
+		return strconv.ParseFloat(element, 64)
+	})
+}
+
+// MapString returns value of Map(String, String)
+func (result Result) MapString(column string) (map[string]string, error) {
+	return GetMap(result, column,
+		func(key string) (string, error) { return key, nil },
+		func(value string) (string, error) { return value, nil })
+}
+
+func arrayError(value string, err error) error {
+	err = fmt.Errorf("can't convert value %s to array: %s", value, err.Error())
+
+	cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+	return err
+}
+
+func mapError(value string, err error) error {
+	err = fmt.Errorf("can't convert value %s to map: %s", value, err.Error())
+
+	cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+	return err
+}
@@ -0,0 +1,119 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// breaker fails queries fast after amount of consecutive failures of server until cooldown is passed
+// After the cooldown one query is sent to check if the server is recovered (half-open state)
+type breaker struct {
+	mux         sync.Mutex
+	threshold   int
+	cooldown    time.Duration
+	failures    int
+	openedUntil time.Time
+	probing     bool
+}
+
+// CircuitBreaker turns on failing queries fast with ErrCircuitOpen after amount of consecutive failures
+// of server (unavailable host or gateway errors) until cooldown is passed
+// After the cooldown one query checks if the server is recovered (zero failures turns the breaker off)
+// The breaker is shared with clones of the connection
+func (conn *Conn) CircuitBreaker(failures int, cooldown time.Duration) {
+	if failures < 0 {
+		return
+	}
+
+	conn.breaker.mux.Lock()
+	conn.breaker.threshold = failures
+	conn.breaker.cooldown = cooldown
+	conn.breaker.failures = 0
+	conn.breaker.probing = false
+	conn.breaker.mux.Unlock()
+
+	message := fmt.Sprintf("Set circuit breaker failures = %d and cooldown = %s", failures, cooldown)
+	cfg.logger.debug(message)
+}
+
+// allow checks if query can be sent and if the query is the probe of recovery which has to be passed to report
+func (b *breaker) allow() (allowed, probe bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if b.threshold == 0 || b.failures < b.threshold {
+		return true, false
+	}
+
+	if b.probing || time.Now().Before(b.openedUntil) {
+		return false, false
+	}
+
+	// the only query checks recovery while the rest fail fast
+	b.probing = true
+
+	return true, true
+}
+
+// report counts result of sent query (probe is returned by allow)
+func (b *breaker) report(host string, probe bool, err error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	// queries sent before the breaker is opened don't finish the probe
+	if probe {
+		b.probing = false
+	}
+
+	if !isServerFailure(err) {
+		// local errors (e.g. cancelled context) don't show if the server is recovered
+		var queryErr *QueryError
+		if err != nil && !errors.As(err, &queryErr) {
+			return
+		}
+
+		if b.threshold > 0 && b.failures >= b.threshold {
+			message := fmt.Sprintf("Circuit breaker of %s is closed", host)
+			cfg.logger.info(message)
+		}
+
+		b.failures = 0
+
+		return
+	}
+
+	b.failures++
+
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openedUntil = time.Now().Add(b.cooldown)
+
+		message := fmt.Sprintf("Catch warning circuit breaker of %s is opened for %s after %d failures", host, b.cooldown, b.failures)
+		cfg.logger.warn(message)
+	}
+}
+
+// isServerFailure checks if query failed because server is unavailable
+func isServerFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var queryErr *QueryError
+	if errors.As(err, &queryErr) {
+		switch queryErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+
+		return false
+	}
+
+	var urlErr *url.Error
+
+	return errors.As(err, &urlErr)
+}
@@ -0,0 +1,176 @@
+package clickhouse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SelectBuilder builds simple SELECT query with values quoted by Quote
+type SelectBuilder struct {
+	columns []string
+	table   string
+	where   []string
+	groupBy []string
+	orderBy []string
+	limit   int
+	err     error
+}
+
+// Select starts building of query which selects passed columns (all columns if there are no columns)
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: columns}
+}
+
+// From sets table of query (e.g. db.table)
+func (builder *SelectBuilder) From(table string) *SelectBuilder {
+	builder.table = table
+
+	return builder
+}
+
+// Where adds condition with ? placeholders replaced with quoted args in order
+// Several conditions are joined with AND
+func (builder *SelectBuilder) Where(condition string, args ...interface{}) *SelectBuilder {
+	bound, err := bind(condition, args)
+	if err != nil && builder.err == nil {
+		builder.err = err
+	}
+
+	builder.where = append(builder.where, bound)
+
+	return builder
+}
+
+// GroupBy adds expressions of GROUP BY clause
+func (builder *SelectBuilder) GroupBy(columns ...string) *SelectBuilder {
+	builder.groupBy = append(builder.groupBy, columns...)
+
+	return builder
+}
+
+// OrderBy adds expressions of ORDER BY clause (e.g. "created DESC")
+func (builder *SelectBuilder) OrderBy(columns ...string) *SelectBuilder {
+	builder.orderBy = append(builder.orderBy, columns...)
+
+	return builder
+}
+
+// Limit sets amount of rows of result (zero removes the limit)
+func (builder *SelectBuilder) Limit(limit int) *SelectBuilder {
+	builder.limit = limit
+
+	return builder
+}
+
+// Build returns query or the first error of building
+func (builder *SelectBuilder) Build() (string, error) {
+	if builder.err != nil {
+		return "", builder.err
+	}
+
+	if len(builder.table) == 0 {
+		err := errors.New("can't build query without table")
+
+		cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+		return "", err
+	}
+
+	columns := "*"
+	if len(builder.columns) > 0 {
+		columns = strings.Join(builder.columns, ", ")
+	}
+
+	query := "SELECT " + columns + " FROM " + builder.table
+
+	if len(builder.where) > 0 {
+		query += " WHERE (" + strings.Join(builder.where, ") AND (") + ")"
+	}
+
+	if len(builder.groupBy) > 0 {
+		query += " GROUP BY " + strings.Join(builder.groupBy, ", ")
+	}
+
+	if len(builder.orderBy) > 0 {
+		query += " ORDER BY " + strings.Join(builder.orderBy, ", ")
+	}
+
+	if builder.limit > 0 {
+		query += " LIMIT " + strconv.Itoa(builder.limit)
+	}
+
+	return query, nil
+}
+
+// Merge returns expression which merges states of AggregateFunction column with -Merge combinator
+// e.g. Merge("sum", "bytes_state") returns sumMerge(bytes_state) and parameters of parametric function are kept
+// so Merge("quantiles(0.5, 0.9)", "latency_state") returns quantilesMerge(0.5, 0.9)(latency_state)
+func Merge(function, column string) string {
+	function = strings.TrimSpace(function)
+
+	name, params := function, ""
+	if index := strings.IndexByte(function, '('); index >= 0 {
+		name, params = strings.TrimSpace(function[:index]), function[index:]
+	}
+
+	return name + "Merge" + params + "(" + column + ")"
+}
+
+// bind replaces ? placeholders of condition with quoted args
+// Placeholders inside quoted strings and identifiers are kept as is
+func bind(condition string, args []interface{}) (string, error) {
+	var (
+		result strings.Builder
+		quote  byte
+		index  int
+	)
+
+	for i := 0; i < len(condition); i++ {
+		char := condition[i]
+
+		switch {
+		case quote != 0:
+			if char == '\\' && i+1 < len(condition) {
+				result.WriteByte(char)
+				i++
+				char = condition[i]
+			} else if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '`' || char == '"':
+			quote = char
+		case char == '?':
+			if index >= len(args) {
+				err := fmt.Errorf("condition %s has more placeholders than %d args", condition, len(args))
+
+				cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+				return condition, err
+			}
+
+			value, err := Quote(args[index])
+			if err != nil {
+				return condition, err
+			}
+
+			result.WriteString(value)
+			index++
+
+			continue
+		}
+
+		result.WriteByte(char)
+	}
+
+	if index != len(args) {
+		err := fmt.Errorf("condition %s has %d placeholders for %d args", condition, index, len(args))
+
+		cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+		return condition, err
+	}
+
+	return result.String(), nil
+}
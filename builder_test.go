@@ -0,0 +1,55 @@
+package clickhouse
+
+import "testing"
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		args      []interface{}
+		want      string
+	}{
+		{"no placeholders", "id = 1", nil, "id = 1"},
+		{"placeholders", "id = ? AND name = ?", []interface{}{1, "a'b"}, `id = 1 AND name = 'a\'b'`},
+		{"slice", "id IN ?", []interface{}{[]int{1, 2}}, "id IN [1,2]"},
+		{"nil", "name = ?", []interface{}{nil}, "name = NULL"},
+		{"placeholder in string", "name = '?' AND id = ?", []interface{}{1}, "name = '?' AND id = 1"},
+		{"escaped quote in string", `name = 'a\'?' AND id = ?`, []interface{}{1}, `name = 'a\'?' AND id = 1`},
+		{"placeholder in backticks", "`col?` = ?", []interface{}{1}, "`col?` = 1"},
+		{"placeholder in double quotes", `"col?" = ?`, []interface{}{1}, `"col?" = 1`},
+		{"placeholder in arg", "name = ?", []interface{}{"?"}, "name = '?'"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := bind(test.condition, test.args)
+			if err != nil {
+				t.Fatalf("bind(%q) returns error %s", test.condition, err.Error())
+			}
+
+			if got != test.want {
+				t.Errorf("bind(%q) = %q; want %q", test.condition, got, test.want)
+			}
+		})
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		args      []interface{}
+	}{
+		{"more placeholders", "a = ? AND b = ?", []interface{}{1}},
+		{"more args", "a = ?", []interface{}{1, 2}},
+		{"unsupported arg", "a = ?", []interface{}{struct{}{}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := bind(test.condition, test.args); err == nil {
+				t.Errorf("bind(%q) doesn't return error", test.condition)
+			}
+		})
+	}
+}
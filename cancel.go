@@ -0,0 +1,132 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// inflight tracks executing queries of connection and its clones so they can be cancelled at once
+type inflight struct {
+	mux     sync.Mutex
+	next    uint64
+	queries map[uint64]*inflightQuery
+}
+
+type inflightQuery struct {
+	cancel  context.CancelFunc
+	queryID string
+}
+
+func newInflight() *inflight {
+	return &inflight{queries: map[uint64]*inflightQuery{}}
+}
+
+// add registers executing query and returns its key
+func (tracker *inflight) add(cancel context.CancelFunc, queryID string) uint64 {
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+
+	tracker.next++
+	tracker.queries[tracker.next] = &inflightQuery{cancel: cancel, queryID: queryID}
+
+	return tracker.next
+}
+
+// setQueryID sets ID of query reported by server if the query doesn't have it yet
+func (tracker *inflight) setQueryID(key uint64, queryID string) {
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+
+	query, ok := tracker.queries[key]
+	if ok && len(query.queryID) == 0 {
+		query.queryID = queryID
+	}
+}
+
+// done cancels context of finished query and forgets it
+func (tracker *inflight) done(key uint64) {
+	tracker.mux.Lock()
+	query, ok := tracker.queries[key]
+	delete(tracker.queries, key)
+	tracker.mux.Unlock()
+
+	if ok {
+		query.cancel()
+	}
+}
+
+// cancelAll cancels contexts of all executing queries and returns their known IDs
+func (tracker *inflight) cancelAll() (int, []string) {
+	tracker.mux.Lock()
+	defer tracker.mux.Unlock()
+
+	ids := make([]string, 0, len(tracker.queries))
+	for _, query := range tracker.queries {
+		query.cancel()
+
+		if len(query.queryID) > 0 {
+			ids = append(ids, query.queryID)
+		}
+	}
+
+	return len(tracker.queries), ids
+}
+
+// inflightReader forgets query when its response is closed
+type inflightReader struct {
+	io.ReadCloser
+	done func()
+}
+
+func (reader inflightReader) Close() error {
+	err := reader.ReadCloser.Close()
+	reader.done()
+
+	return err
+}
+
+// queryID returns query_id set by settings of context or connection
+func (conn *Conn) queryID(ctx context.Context) string {
+	if id, ok := getSettings(ctx)["query_id"]; ok {
+		return id
+	}
+
+	conn.mux.Lock()
+	defer conn.mux.Unlock()
+
+	return conn.settings["query_id"]
+}
+
+// CancelAll cancels all executing queries of the connection and its clones (e.g. on graceful shutdown)
+// Queries with known query_id (set by client or reported by server) are also killed on server with KILL QUERY
+// because server may continue queries after disconnecting of client
+func (conn *Conn) CancelAll() error {
+	amount, ids := conn.inflight.cancelAll()
+
+	message := fmt.Sprintf("Cancel %d executing queries", amount)
+	cfg.logger.info(message)
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	sort.Strings(ids)
+
+	quoted := make([]string, 0, len(ids))
+	for _, id := range ids {
+		quoted = append(quoted, "'"+escapeValuesString(id)+"'")
+	}
+
+	query := fmt.Sprintf("KILL QUERY WHERE query_id IN (%s) ASYNC", strings.Join(quoted, ", "))
+
+	message = fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	_, err := conn.exec(context.Background(), query, nil)
+
+	return err
+}
@@ -3,13 +3,18 @@ package clickhouse
 import (
 	"bufio"
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -21,8 +26,17 @@ const (
 	GigaByte = 1024 * MegaByte
 )
 
+const version = "1.0.0"
+
+const defaultBufferSize = 4096
+
+const defaultMaxRedirects = 10
+
+// tooManyQueriesWait is base of backoff for queries rejected by overloaded server if attempts wait isn't set
+const tooManyQueriesWait = time.Second
+
 type Conn struct {
-	Limiter
+	*Limiter
 
 	host           string
 	port           int
@@ -33,33 +47,103 @@ type Conn struct {
 	sendTimeout    int32
 	receiveTimeout int32
 	compression    int32
+	bodyCompress   int32
+	bodyLevel      int32
+	waitEndOfQuery int32
+	bestEffort     int32
+	autoDedup      int32
+	keepAlive      int32
+	rawFields      int32
+	lenientScan    int32
+	emptyAsZero    int32
+	ignoreCase     int32
+	uncompressed   int32
+	checkSettings  int32
+	nonFinite      int32
+	skipBadRows    int32
+	bufferSize     int32
+	quoteIdents    int32
+	checkInserts   int32
 	attemptsAmount uint32
 	attemptWait    uint32
-	protocol	   string
-	mux sync.Mutex
+	getMaxLength   uint32
+	maxQueryBytes  uint32
+	connectRetries uint32
+	connectWait    uint32
+	maxRedirects   uint32
+	totalTimeout   uint32
+	defaultLimit   uint32
+	protocol       string
+	database       string
+	defaultFormat  Format
+	token          string
+	lastInsertID   string
+	interceptor    func(query string) (string, error)
+	headers        map[string]string
+	settings       map[string]string
+	knownSettings  *knownSettings
+	transport      *sharedTransport
+	breaker        *breaker
+	inflight       *inflight
+	mux            sync.Mutex
 }
 
 type Iter struct {
 	conn       *Conn
-	columns    map[string]int
+	query      string
+	format     Format
+	decoder    RowDecoder
+	buffer     *bufio.Reader
 	readCloser io.ReadCloser
-	reader     *bufio.Reader
 	err        error
 	Result     Result
+	skipped    int
+	rows       int
+	maxRows    int
+	reuseRows  bool
+	location   *time.Location
+	queryID    string
+	rowsHint   uint64
+	index      map[string]string
+	nullRepr   string
 	isClosed   bool
 }
 
 type Result struct {
-	data map[string]string
+	conn     *Conn
+	data     map[string]string
+	index    map[string]string
+	types    map[string]string
+	escaped  bool
+	location *time.Location
 }
 
 type Format string
 
 const (
-	TSV          Format = "TabSeparated"
-	TSVWithNames Format = "TabSeparatedWithNames"
-	CSV          Format = "CSV"
-	CSVWithNames Format = "CSVWithNames"
+	TSV                  Format = "TabSeparated"
+	TSVWithNames         Format = "TabSeparatedWithNames"
+	TSVWithNamesAndTypes Format = "TabSeparatedWithNamesAndTypes"
+	CSV                  Format = "CSV"
+	CSVWithNames         Format = "CSVWithNames"
+	JSONEachRow          Format = "JSONEachRow"
+	RowBinary            Format = "RowBinary"
+	Native               Format = "Native"
+	Parquet              Format = "Parquet"
+	Pretty               Format = "Pretty"
+	PrettyCompact        Format = "PrettyCompact"
+)
+
+// NonFiniteMode describes handling of non-finite float values
+type NonFiniteMode int32
+
+const (
+	// NonFiniteAllow returns NaN and infinities as they are
+	NonFiniteAllow NonFiniteMode = iota
+	// NonFiniteError returns error for NaN and infinities
+	NonFiniteError
+	// NonFiniteZero returns zero for NaN and infinities
+	NonFiniteZero
 )
 
 type config struct {
@@ -87,18 +171,89 @@ func New(host string, port int, user string, pass string) *Conn {
 	cfg.logger.info("Clickhouse is initialized")
 
 	return &Conn{
+		Limiter:        &Limiter{},
+		breaker:        &breaker{},
+		inflight:       newInflight(),
 		host:           host,
 		port:           port,
 		user:           user,
 		pass:           pass,
-		protocol: 		"https",
+		protocol:       "https",
+		defaultFormat:  TSVWithNames,
+		headers:        defaultHeaders(),
+		settings:       map[string]string{},
+		knownSettings:  &knownSettings{},
+		transport:      &sharedTransport{transport: newTransport()},
 		connectTimeout: -1,
 		receiveTimeout: -1,
 		sendTimeout:    -1,
 		maxMemoryUsage: -1,
 		compression:    -1,
+		keepAlive:      1,
 		attemptsAmount: 1,
-		attemptWait:    0}
+		attemptWait:    0,
+		maxRedirects:   defaultMaxRedirects}
+}
+
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialContext
+
+	return transport
+}
+
+// sharedTransport keeps HTTP transport shared by connection and its clones
+// The transport is replaced with changed copy instead of changing it in place while queries use it
+type sharedTransport struct {
+	mux       sync.Mutex
+	transport *http.Transport
+}
+
+func (shared *sharedTransport) get() *http.Transport {
+	shared.mux.Lock()
+	defer shared.mux.Unlock()
+
+	return shared.transport
+}
+
+// update replaces transport with its copy changed by passed function
+// Executing queries finish with the previous transport while its idle connections are closed
+func (shared *sharedTransport) update(change func(transport *http.Transport)) {
+	shared.mux.Lock()
+	previous := shared.transport
+	shared.transport = previous.Clone()
+	change(shared.transport)
+	shared.mux.Unlock()
+
+	previous.CloseIdleConnections()
+}
+
+// knownSettings keeps names of server settings loaded once and shared by connection and its clones
+type knownSettings struct {
+	once  sync.Once
+	mux   sync.Mutex
+	names map[string]bool
+}
+
+// get returns names of settings or nil if they aren't loaded
+func (known *knownSettings) get() map[string]bool {
+	known.mux.Lock()
+	defer known.mux.Unlock()
+
+	return known.names
+}
+
+func (known *knownSettings) set(names map[string]bool) {
+	known.mux.Lock()
+	known.names = names
+	known.mux.Unlock()
+}
+
+func defaultHeaders() map[string]string {
+	return map[string]string{
+		"User-Agent":    "golang-clickhouse/" + version,
+		"Pragma":        "no-cache",
+		"Cache-Control": "no-cache"}
 }
 
 // Debug sets logger for debug
@@ -131,7 +286,8 @@ func Fatal(callback func(message string)) {
 	cfg.logger.debug("Set custom fatal logger")
 }
 
-// Attempts sets amount of attempt query execution
+// Attempts sets amount of attempt query execution and wait between them (wait in seconds)
+// The first attempt is sent immediately and the retry number N waits N * wait before sending
 func (conn *Conn) Attempts(amount int, wait int) {
 	atomic.StoreUint32(&conn.attemptsAmount, uint32(amount))
 	atomic.StoreUint32(&conn.attemptWait, uint32(wait))
@@ -140,318 +296,2482 @@ func (conn *Conn) Attempts(amount int, wait int) {
 	cfg.logger.debug(message)
 }
 
-// Protocol sets new protocol value
-func (conn *Conn) Protocol(protocol string) {
-	conn.mux.Lock()
-	conn.protocol = protocol
-	conn.mux.Unlock()
-	message := fmt.Sprintf("Set protocol = %s", protocol)
-	cfg.logger.debug(message)
+// getAttempts returns amount of attempts and wait between them overridden by query options of context
+func (conn *Conn) getAttempts(ctx context.Context) (uint32, uint32) {
+	amount := atomic.LoadUint32(&conn.attemptsAmount)
+	wait := atomic.LoadUint32(&conn.attemptWait)
+
+	options, ok := getAttemptsOptions(ctx)
+	if !ok {
+		return amount, wait
+	}
+
+	if options.Attempts > 0 {
+		amount = uint32(options.Attempts)
+	}
+
+	if options.AttemptWait > 0 {
+		wait = uint32(options.AttemptWait)
+	} else if options.AttemptWait < 0 {
+		wait = 0
+	}
+
+	return amount, wait
 }
 
-// MaxMemoryUsage sets new maximum memory usage value
-func (conn *Conn) MaxMemoryUsage(limit int) {
-	if limit < 0 {
+// ConnectRetries sets amount of retries to establish connection and wait between them (wait in milliseconds)
+// Only connection establishing is retried so it's safe for any query unlike Attempts
+func (conn *Conn) ConnectRetries(amount int, wait int) {
+	if amount < 0 || wait < 0 {
 		return
 	}
 
-	atomic.StoreInt32(&conn.maxMemoryUsage, int32(limit))
+	atomic.StoreUint32(&conn.connectRetries, uint32(amount))
+	atomic.StoreUint32(&conn.connectWait, uint32(wait))
 
-	message := fmt.Sprintf("Set max_memory_usage = %d", limit)
+	message := fmt.Sprintf("Set connect retries amount (%d) and wait (%d milliseconds)", amount, wait)
 	cfg.logger.debug(message)
 }
 
-// ConnectTimeout sets new connection timeout
-func (conn *Conn) ConnectTimeout(timeout int) {
-	if timeout < 0 {
+// MaxRedirects sets amount of followed redirects (e.g. 307 and 308 responses of load balancer)
+// Body of the query is sent again on redirect if it's possible to rewind it (zero turns redirects off)
+func (conn *Conn) MaxRedirects(amount int) {
+	if amount < 0 {
 		return
 	}
 
-	atomic.StoreInt32(&conn.connectTimeout, int32(timeout))
+	atomic.StoreUint32(&conn.maxRedirects, uint32(amount))
 
-	message := fmt.Sprintf("Set connect_timeout = %d s", timeout)
+	message := fmt.Sprintf("Set max redirects = %d", amount)
 	cfg.logger.debug(message)
 }
 
-// SendTimeout sets new send timeout
-func (conn *Conn) SendTimeout(timeout int) {
+// TotalTimeout sets time budget of query shared by all its attempts and waits between them (zero turns it off)
+// The query fails as soon as the budget is exhausted or the rest of it is less than wait before the next attempt
+func (conn *Conn) TotalTimeout(timeout time.Duration) {
 	if timeout < 0 {
 		return
 	}
 
-	atomic.StoreInt32(&conn.sendTimeout, int32(timeout))
+	atomic.StoreUint32(&conn.totalTimeout, uint32(timeout.Milliseconds()))
 
-	message := fmt.Sprintf("Set send_timeout = %d s", timeout)
+	message := fmt.Sprintf("Set total timeout = %s", timeout)
 	cfg.logger.debug(message)
 }
 
-// Compression sets new send timeout
-func (conn *Conn) Compression(compression bool) {
-	var compInt int32 = 0
-	if compression {
-		compInt = 1
+// Protocol sets new protocol value
+func (conn *Conn) Protocol(protocol string) {
+	conn.mux.Lock()
+	conn.protocol = protocol
+	conn.mux.Unlock()
+	message := fmt.Sprintf("Set protocol = %s", protocol)
+	cfg.logger.debug(message)
+}
+
+// DefaultFormat sets format of fetching queries without FORMAT clause
+// The format has to be supported by iterator (TabSeparatedWithNames, TabSeparatedWithNamesAndTypes, CSVWithNames, JSONEachRow or format with registered decoder)
+func (conn *Conn) DefaultFormat(format Format) {
+	if !isIterable(format) {
+		message := fmt.Sprintf("Catch warning format `%s` can't be fetched with iterator", format)
+		cfg.logger.warn(message)
+
+		return
 	}
 
-	atomic.StoreInt32(&conn.compression, compInt)
+	conn.mux.Lock()
+	conn.defaultFormat = format
+	conn.mux.Unlock()
 
-	message := fmt.Sprintf("Set compression = %d", conn.compression)
+	message := fmt.Sprintf("Set default format = %s", format)
 	cfg.logger.debug(message)
 }
 
-// ReceiveTimeout sets new receive timeout
-func (conn *Conn) ReceiveTimeout(timeout int) {
-	atomic.StoreInt32(&conn.receiveTimeout, int32(timeout))
+// Database sets default database for queries
+func (conn *Conn) Database(name string) {
+	conn.mux.Lock()
+	conn.database = name
+	conn.mux.Unlock()
+	message := fmt.Sprintf("Set database = %s", name)
+	cfg.logger.debug(message)
+}
 
-	message := fmt.Sprintf("Set receive_timeout = %d s", timeout)
+// WithDatabase returns copy of connection with overridden default database
+// The copy shares requests limiter with the original connection
+func (conn *Conn) WithDatabase(name string) *Conn {
+	clone := conn.clone()
+	clone.database = name
+
+	message := fmt.Sprintf("Clone connection with database = %s", name)
 	cfg.logger.debug(message)
+
+	return clone
 }
 
-// Exec executes new query
-func (conn *Conn) Exec(query string) error {
-	conn.waitForRest()
-	conn.increase()
-	defer conn.reduce()
+func (conn *Conn) clone() *Conn {
+	conn.mux.Lock()
+	defer conn.mux.Unlock()
 
-	return conn.ForcedExec(query)
+	return &Conn{
+		Limiter:        conn.Limiter,
+		breaker:        conn.breaker,
+		inflight:       conn.inflight,
+		host:           conn.host,
+		port:           conn.port,
+		user:           conn.user,
+		pass:           conn.pass,
+		protocol:       conn.protocol,
+		database:       conn.database,
+		defaultFormat:  conn.defaultFormat,
+		token:          conn.token,
+		interceptor:    conn.interceptor,
+		headers:        copyMap(conn.headers),
+		settings:       copyMap(conn.settings),
+		knownSettings:  conn.knownSettings,
+		transport:      conn.transport,
+		connectTimeout: atomic.LoadInt32(&conn.connectTimeout),
+		receiveTimeout: atomic.LoadInt32(&conn.receiveTimeout),
+		sendTimeout:    atomic.LoadInt32(&conn.sendTimeout),
+		maxMemoryUsage: atomic.LoadInt32(&conn.maxMemoryUsage),
+		compression:    atomic.LoadInt32(&conn.compression),
+		bodyCompress:   atomic.LoadInt32(&conn.bodyCompress),
+		bodyLevel:      atomic.LoadInt32(&conn.bodyLevel),
+		waitEndOfQuery: atomic.LoadInt32(&conn.waitEndOfQuery),
+		bestEffort:     atomic.LoadInt32(&conn.bestEffort),
+		autoDedup:      atomic.LoadInt32(&conn.autoDedup),
+		keepAlive:      atomic.LoadInt32(&conn.keepAlive),
+		rawFields:      atomic.LoadInt32(&conn.rawFields),
+		lenientScan:    atomic.LoadInt32(&conn.lenientScan),
+		emptyAsZero:    atomic.LoadInt32(&conn.emptyAsZero),
+		ignoreCase:     atomic.LoadInt32(&conn.ignoreCase),
+		uncompressed:   atomic.LoadInt32(&conn.uncompressed),
+		checkSettings:  atomic.LoadInt32(&conn.checkSettings),
+		nonFinite:      atomic.LoadInt32(&conn.nonFinite),
+		skipBadRows:    atomic.LoadInt32(&conn.skipBadRows),
+		bufferSize:     atomic.LoadInt32(&conn.bufferSize),
+		quoteIdents:    atomic.LoadInt32(&conn.quoteIdents),
+		checkInserts:   atomic.LoadInt32(&conn.checkInserts),
+		attemptsAmount: atomic.LoadUint32(&conn.attemptsAmount),
+		attemptWait:    atomic.LoadUint32(&conn.attemptWait),
+		getMaxLength:   atomic.LoadUint32(&conn.getMaxLength),
+		maxQueryBytes:  atomic.LoadUint32(&conn.maxQueryBytes),
+		connectRetries: atomic.LoadUint32(&conn.connectRetries),
+		connectWait:    atomic.LoadUint32(&conn.connectWait),
+		maxRedirects:   atomic.LoadUint32(&conn.maxRedirects),
+		totalTimeout:   atomic.LoadUint32(&conn.totalTimeout),
+		defaultLimit:   atomic.LoadUint32(&conn.defaultLimit)}
 }
 
-// ForcedExec executes new query without requests limits
-func (conn *Conn) ForcedExec(query string) error {
-	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+// Header sets HTTP header sent with every query (empty value removes the header)
+func (conn *Conn) Header(name, value string) {
+	conn.mux.Lock()
+	if len(value) == 0 {
+		delete(conn.headers, name)
+	} else {
+		conn.headers[name] = value
+	}
+	conn.mux.Unlock()
+
+	message := fmt.Sprintf("Set header %s = %s", name, value)
 	cfg.logger.debug(message)
+}
 
-	reader, err := conn.doQuery(query)
-	if err != nil {
-		message = fmt.Sprintf("Catch error %s", err.Error())
-		cfg.logger.error(message)
+// BearerToken sets token sent with Authorization header instead of credentials in URL (empty token turns it off)
+func (conn *Conn) BearerToken(token string) {
+	conn.mux.Lock()
+	conn.token = token
+	conn.mux.Unlock()
 
-		return err
+	cfg.logger.debug("Set bearer token")
+}
+
+// UserAgent sets User-Agent header to identify client application
+func (conn *Conn) UserAgent(ua string) {
+	conn.Header("User-Agent", ua)
+}
+
+func copyMap(src map[string]string) map[string]string {
+	dst := make(map[string]string, len(src))
+	for key, value := range src {
+		dst[key] = value
 	}
 
-	defer reader.Close()
+	return dst
+}
 
-	_, err = ioutil.ReadAll(reader)
-	if err != nil {
-		return err
+// Setting sets custom setting sent with every query (empty value removes the setting)
+func (conn *Conn) Setting(name, value string) {
+	conn.mux.Lock()
+	if len(value) == 0 {
+		delete(conn.settings, name)
+	} else {
+		conn.settings[name] = value
 	}
+	conn.mux.Unlock()
 
-	message = fmt.Sprintf("The query is executed %s", cutOffQuery(query, 500))
+	known := conn.knownSettings.get()
+
+	message := fmt.Sprintf("Set %s = %s", name, value)
 	cfg.logger.debug(message)
 
-	return nil
+	if known != nil && !known[name] {
+		warnUnknownSetting(name)
+	}
 }
 
-// InsertBatch inserts TSV data into `database.table` table
-func (conn *Conn) InsertBatch(database, table string, columns []string, format Format, tsvReader io.Reader) error {
-	var query string
-	if len(columns) == 0 {
-		query = fmt.Sprintf("INSERT INTO %s.%s FORMAT %s\n", database, table, format)
-	} else {
-		query = fmt.Sprintf("INSERT INTO %s.%s (%s) FORMAT %s\n", database, table, strings.Join(columns, ", "), format)
+// CSVDelimiter sets delimiter of fields of CSV formats (format_csv_delimiter setting) for fetching and inserting
+// Built-in decoder of CSVWithNames splits fields by the delimiter which can be overridden per query with
+// WithSetting(ctx, "format_csv_delimiter", value)
+// It returns error if delimiter is quote, line break or isn't ASCII symbol
+func (conn *Conn) CSVDelimiter(delimiter byte) error {
+	if delimiter == '"' || delimiter == '\r' || delimiter == '\n' || delimiter == 0 || delimiter > 127 {
+		err := fmt.Errorf("can't use %q as delimiter of CSV fields", delimiter)
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return err
 	}
 
-	reader := bufio.NewReader(tsvReader)
+	conn.Setting("format_csv_delimiter", string(delimiter))
 
-	var (
-		bs  []byte
-		err error
-	)
+	return nil
+}
 
-	for {
-		bs, err = reader.ReadBytes('\b')
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return err
-		}
+// csvDelimiter returns delimiter of CSV fields of query settings or comma by default
+func (conn *Conn) csvDelimiter(ctx context.Context) rune {
+	value, ok := getSettings(ctx)["format_csv_delimiter"]
+	if !ok {
+		conn.mux.Lock()
+		value = conn.settings["format_csv_delimiter"]
+		conn.mux.Unlock()
+	}
 
-		query += string(bs)
+	if len(value) != 1 {
+		return ','
 	}
 
-	query += "\n"
+	return rune(value[0])
+}
 
-	err = conn.Exec(query)
+// NullRepresentation sets representation of NULL in TabSeparated formats (format_tsv_null_representation setting)
+// Fetched fields equal to the representation are read as NULL by result accessors and Scan
+// Empty representation or \N returns the default
+func (conn *Conn) NullRepresentation(representation string) {
+	if representation == `\N` {
+		representation = ""
+	}
 
-	return err
+	conn.Setting("format_tsv_null_representation", representation)
 }
 
-// Fetch executes new query and fetches all data
-func (conn *Conn) Fetch(query string) (Iter, error) {
-	conn.waitForRest()
-	conn.increase()
-	defer conn.reduce()
+// nullRepresentation returns representation of NULL of query settings or empty string for the default \N
+func (conn *Conn) nullRepresentation(ctx context.Context) string {
+	value, ok := getSettings(ctx)["format_tsv_null_representation"]
+	if !ok {
+		conn.mux.Lock()
+		value = conn.settings["format_tsv_null_representation"]
+		conn.mux.Unlock()
+	}
 
-	return conn.ForcedFetch(query)
+	if value == `\N` {
+		return ""
+	}
+
+	return value
 }
 
-// ForcedFetch executes new query and fetches all data without requests limits
-func (conn *Conn) ForcedFetch(query string) (Iter, error) {
-	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
-	cfg.logger.debug(message)
+// DefaultLimit adds LIMIT clause into fetched SELECT queries without the outermost LIMIT (zero turns it off)
+// LIMIT of subqueries and LIMIT BY aren't counted and UNION queries are kept as is
+// Queries of system tables made by helpers (e.g. Tables, Columns or Mutate) aren't limited
+func (conn *Conn) DefaultLimit(limit int) {
+	if limit < 0 {
+		return
+	}
 
-	re := regexp.MustCompile("(FORMAT [A-Za-z0-9]+)? *;? *$")
-	query = re.ReplaceAllString(query, " FORMAT TabSeparatedWithNames")
+	atomic.StoreUint32(&conn.defaultLimit, uint32(limit))
 
-	iter := Iter{conn: conn}
+	message := fmt.Sprintf("Set default limit = %d", limit)
+	cfg.logger.debug(message)
+}
+
+// MaxResultRows limits amount of rows of query result so exceeding queries fail with error matching ErrResultTooLarge
+// Zero limit removes the limitation
+// If the server starts sending the response before the limit is reached the failure is reported inside the data stream
+func (conn *Conn) MaxResultRows(limit int) {
+	if limit < 0 {
+		return
+	}
 
-	var err error
-	iter.readCloser, err = conn.doQuery(query)
+	if limit == 0 {
+		conn.Setting("max_result_rows", "")
+		conn.Setting("result_overflow_mode", "")
 
-	if err != nil {
-		return iter, err
+		return
 	}
 
-	iter.columns = make(map[string]int)
+	conn.Setting("max_result_rows", strconv.Itoa(limit))
+	conn.Setting("result_overflow_mode", "throw")
+}
 
-	cfg.logger.debug("Open stream to fetch")
+// InsertQuorum sets amount of replicas which have to write data of every insert before the insert succeeds (insert_quorum)
+// so acknowledged inserts aren't lost with one replica (zero removes the quorum)
+// Reads of such data from other replicas can be done with WithSequentialConsistency
+func (conn *Conn) InsertQuorum(replicas int) {
+	if replicas < 0 {
+		return
+	}
 
-	iter.reader = bufio.NewReader(iter.readCloser)
-	bytes, hasMore := iter.read()
-	if !hasMore {
-		err := errors.New("can't get columns names")
+	if replicas == 0 {
+		conn.Setting("insert_quorum", "")
 
-		message := fmt.Sprintf("Catch error %s", err.Error())
-		cfg.logger.fatal(message)
+		return
+	}
+
+	conn.Setting("insert_quorum", strconv.Itoa(replicas))
+}
 
-		return iter, err
+// InsertQuorumTimeout sets time of waiting for quorum of insert (insert_quorum_timeout) after which the insert fails
+// Zero timeout returns the server default
+func (conn *Conn) InsertQuorumTimeout(timeout time.Duration) {
+	if timeout < 0 {
+		return
 	}
 
-	line := string(bytes)
+	if timeout == 0 {
+		conn.Setting("insert_quorum_timeout", "")
 
-	matches := strings.Split(line, "\t")
-	for index, column := range matches {
-		iter.columns[column] = index
+		return
 	}
 
-	cfg.logger.debug("Load fields names")
+	conn.Setting("insert_quorum_timeout", strconv.FormatInt(timeout.Milliseconds(), 10))
+}
 
-	return iter, nil
+// LogComment sets log_comment setting which is written into system.query_log for every query (empty comment removes it)
+func (conn *Conn) LogComment(comment string) {
+	conn.Setting("log_comment", comment)
 }
 
-// FetchOne executes new query and fetches one row
-func (conn *Conn) FetchOne(query string) (Result, error) {
-	conn.waitForRest()
-	conn.increase()
-	defer conn.reduce()
+// MaxThreads limits amount of threads of server processing every query (zero returns the server default)
+// Per query value can be set with WithSetting(ctx, "max_threads", value)
+func (conn *Conn) MaxThreads(threads int) {
+	if threads < 0 {
+		return
+	}
 
-	return conn.ForcedFetchOne(query)
+	if threads == 0 {
+		conn.Setting("max_threads", "")
+
+		return
+	}
+
+	conn.Setting("max_threads", strconv.Itoa(threads))
 }
 
-// ForcedFetchOne executes new query and fetches one row without requests limits
-func (conn *Conn) ForcedFetchOne(query string) (Result, error) {
-	iter, err := conn.ForcedFetch(query)
-	if err != nil {
-		message := fmt.Sprintf("Catch error %s", err.Error())
-		cfg.logger.error(message)
+// Priority sets priority of queries so server pauses queries with bigger value while queries with lower value run
+// e.g. 1 for interactive queries and 10 for batch jobs (zero returns the server default which is no priority)
+// Per query value can be set with WithSetting(ctx, "priority", value)
+func (conn *Conn) Priority(priority int) {
+	if priority < 0 {
+		return
+	}
 
-		return Result{}, err
+	if priority == 0 {
+		conn.Setting("priority", "")
+
+		return
 	}
 
-	defer iter.Close()
+	conn.Setting("priority", strconv.Itoa(priority))
+}
 
-	if iter.Next() {
-		return iter.Result, nil
+// Final makes SELECT queries read tables as with FINAL modifier (final setting of ClickHouse 23.2 and later)
+// so rows of ReplacingMergeTree and similar tables are merged before returning
+func (conn *Conn) Final(flag bool) {
+	if flag {
+		conn.Setting("final", "1")
+	} else {
+		conn.Setting("final", "")
 	}
+}
 
-	return Result{}, nil
+// ForcePrimaryKey makes queries fail with error matching ErrIndexNotUsed
+// if they can't use primary key of MergeTree table (force_primary_key setting) instead of scanning the whole table
+func (conn *Conn) ForcePrimaryKey(flag bool) {
+	if flag {
+		conn.Setting("force_primary_key", "1")
+	} else {
+		conn.Setting("force_primary_key", "")
+	}
 }
 
-// Next returns next row of data
-func (iter *Iter) Next() bool {
-	cfg.logger.debug("Check if has more data")
+// ForceIndexByDate makes queries fail with error matching ErrIndexNotUsed
+// if they can't use index by date of MergeTree table (force_index_by_date setting)
+func (conn *Conn) ForceIndexByDate(flag bool) {
+	if flag {
+		conn.Setting("force_index_by_date", "1")
+	} else {
+		conn.Setting("force_index_by_date", "")
+	}
+}
 
-	bytes, hasMore := iter.read()
-	if !hasMore {
-		return false
+// RawFields turns off undoing of TabSeparated escaping by accessors of results (e.g. String, Bytes, JSON and Scan)
+// so values are returned exactly as server sent them (e.g. to stream them into another TabSeparated sink)
+func (conn *Conn) RawFields(flag bool) {
+	var flagInt int32 = 0
+	if flag {
+		flagInt = 1
 	}
 
-	line := string(bytes)
+	atomic.StoreInt32(&conn.rawFields, flagInt)
+
+	message := fmt.Sprintf("Set raw fields = %d", flagInt)
+	cfg.logger.debug(message)
+}
 
-	matches := strings.Split(line, "\t")
+func (conn *Conn) isRawFields() bool {
+	return atomic.LoadInt32(&conn.rawFields) == 1
+}
 
-	iter.Result = Result{}
-	iter.Result.data = make(map[string]string)
-	for column, index := range iter.columns {
-		iter.Result.data[column] = matches[index]
+// StrictScan turns on (by default) or off failing of Scan on lossy conversions (e.g. float value into integer destination)
+// If strict scan is off such values are truncated with warning so drift of schema and structs is visible in logs
+func (conn *Conn) StrictScan(flag bool) {
+	var lenient int32 = 1
+	if flag {
+		lenient = 0
 	}
 
-	cfg.logger.debug("Load new data")
+	atomic.StoreInt32(&conn.lenientScan, lenient)
 
-	return true
+	message := fmt.Sprintf("Set strict scan = %d", 1-lenient)
+	cfg.logger.debug(message)
 }
 
-func (iter *Iter) read() ([]byte, bool) {
-	var bytes []byte
-	bytes, iter.err = iter.reader.ReadBytes('\n')
+func (conn *Conn) isLenientScan() bool {
+	return atomic.LoadInt32(&conn.lenientScan) == 1
+}
 
-	l := len(bytes)
-	if l > 0 {
-		bytes = bytes[0 : len(bytes)-1]
+// EmptyNumericAsZero turns on reading of empty values of numeric columns as zero by integer and float accessors
+// (off by default so empty values fail conversion) e.g. for lenient analytics over imperfect data
+func (conn *Conn) EmptyNumericAsZero(flag bool) {
+	var flagInt int32 = 0
+	if flag {
+		flagInt = 1
 	}
 
-	if iter.err == io.EOF {
-		iter.err = nil
-		iter.Close()
+	atomic.StoreInt32(&conn.emptyAsZero, flagInt)
 
-		return bytes, false
-	} else if iter.err != nil {
-		message := fmt.Sprintf("Catch error %s", iter.err.Error())
-		cfg.logger.fatal(message)
+	message := fmt.Sprintf("Set empty numeric as zero = %d", flagInt)
+	cfg.logger.debug(message)
+}
 
-		return bytes, false
+// CaseInsensitiveColumns turns on case-insensitive lookup of columns by result accessors (e.g. `count` finds `Count`)
+// Exact names are looked up first so the lookup is case-sensitive by default
+func (conn *Conn) CaseInsensitiveColumns(flag bool) {
+	var flagInt int32 = 0
+	if flag {
+		flagInt = 1
 	}
 
-	return bytes, true
-}
+	atomic.StoreInt32(&conn.ignoreCase, flagInt)
 
-// Err returns error of iterator
-func (iter Iter) Err() error {
-	return iter.err
+	message := fmt.Sprintf("Set case-insensitive columns = %d", flagInt)
+	cfg.logger.debug(message)
 }
 
-// Close closes stream
-func (iter Iter) Close() {
-	if !iter.isClosed {
-		iter.readCloser.Close()
+// QuoteColumns turns on quoting of columns names with backticks in inserts
+// so columns named as reserved words (e.g. order or index) can be inserted
+func (conn *Conn) QuoteColumns(flag bool) {
+	var flagInt int32 = 0
+	if flag {
+		flagInt = 1
+	}
 
-		iter.isClosed = true
+	atomic.StoreInt32(&conn.quoteIdents, flagInt)
 
-		cfg.logger.debug("The query is fetched")
-	}
+	message := fmt.Sprintf("Set columns quoting = %d", flagInt)
+	cfg.logger.debug(message)
 }
 
-func (conn *Conn) getFQDN(toConnect bool) string {
-	pass := conn.pass
-	masked := strings.Repeat("*", len(conn.pass))
-
-	if !toConnect {
-		pass = masked
+// ValidateInserts turns on preflight of batch inserts with passed columns
+// Columns of table are loaded with DESCRIBE before sending of data and the insert fails
+// if some columns don't exist or can't be inserted (MATERIALIZED or ALIAS)
+func (conn *Conn) ValidateInserts(flag bool) {
+	var flagInt int32 = 0
+	if flag {
+		flagInt = 1
 	}
 
-	fqnd := fmt.Sprintf("%s:%s@%s:%d", conn.user, pass, conn.host, conn.port)
+	atomic.StoreInt32(&conn.checkInserts, flagInt)
 
-	cfg.Do(func() {
-		message := fmt.Sprintf("Connection FQDN is %s:%s@%s:%d", conn.user, masked, conn.host, conn.port)
-		cfg.logger.info(message)
-	})
+	message := fmt.Sprintf("Set inserts validation = %d", flagInt)
+	cfg.logger.debug(message)
+}
+
+// ValidateSettings turns on validation of custom settings
+// Names of settings are loaded from system.settings before the first query and unknown settings are logged as warnings
+func (conn *Conn) ValidateSettings(flag bool) {
+	var flagInt int32 = 0
+	if flag {
+		flagInt = 1
+	}
+
+	atomic.StoreInt32(&conn.checkSettings, flagInt)
+
+	message := fmt.Sprintf("Set settings validation = %d", flagInt)
+	cfg.logger.debug(message)
+}
+
+func (conn *Conn) validateSettings() {
+	if atomic.LoadInt32(&conn.checkSettings) != 1 {
+		return
+	}
+
+	// names are loaded once for connection and its clones
+	conn.knownSettings.once.Do(func() {
+		// the clone doesn't validate settings so it doesn't recur here
+		loader := conn.clone()
+		loader.ValidateSettings(false)
+
+		iter, err := loader.fetchUnlimited(context.Background(), "SELECT name FROM system.settings")
+		if err != nil {
+			message := fmt.Sprintf("Catch warning can't load settings names: %s", err.Error())
+			cfg.logger.warn(message)
+
+			return
+		}
+
+		defer iter.Close()
+
+		known := make(map[string]bool)
+		for iter.Next() {
+			name, _ := iter.Result.String("name")
+
+			known[name] = true
+		}
+
+		conn.knownSettings.set(known)
+
+		conn.mux.Lock()
+		settings := copyMap(conn.settings)
+		conn.mux.Unlock()
+
+		for name := range settings {
+			if !known[name] {
+				warnUnknownSetting(name)
+			}
+		}
+	})
+}
+
+func warnUnknownSetting(name string) {
+	message := fmt.Sprintf("Catch warning setting %s is unknown by server", name)
+	cfg.logger.warn(message)
+}
+
+// NonFiniteFloats sets how float accessors of results handle nan, -nan, inf, +inf and -inf values
+func (conn *Conn) NonFiniteFloats(mode NonFiniteMode) {
+	atomic.StoreInt32(&conn.nonFinite, int32(mode))
+
+	message := fmt.Sprintf("Set non-finite floats mode = %d", mode)
+	cfg.logger.debug(message)
+}
+
+// SkipBadRows turns on skipping of malformed rows by iterator, Each and FetchAll instead of failing
+func (conn *Conn) SkipBadRows(flag bool) {
+	var flagInt int32 = 0
+	if flag {
+		flagInt = 1
+	}
+
+	atomic.StoreInt32(&conn.skipBadRows, flagInt)
+
+	message := fmt.Sprintf("Set bad rows skipping = %d", flagInt)
+	cfg.logger.debug(message)
+}
+
+func (conn *Conn) isSkippingBadRows() bool {
+	return atomic.LoadInt32(&conn.skipBadRows) == 1
+}
+
+// FetchBufferSize sets size of buffer to read fetched data and to decompress gzip responses (zero is default 4 KB buffer)
+func (conn *Conn) FetchBufferSize(size int) {
+	if size < 0 {
+		return
+	}
+
+	atomic.StoreInt32(&conn.bufferSize, int32(size))
+
+	message := fmt.Sprintf("Set fetch buffer size = %d", size)
+	cfg.logger.debug(message)
+}
+
+func (conn *Conn) getBufferSize() int {
+	size := int(atomic.LoadInt32(&conn.bufferSize))
+	if size == 0 {
+		return defaultBufferSize
+	}
+
+	return size
+}
+
+// MaxMemoryUsage sets new maximum memory usage value
+func (conn *Conn) MaxMemoryUsage(limit int) {
+	if limit < 0 {
+		return
+	}
+
+	atomic.StoreInt32(&conn.maxMemoryUsage, int32(limit))
+
+	message := fmt.Sprintf("Set max_memory_usage = %d", limit)
+	cfg.logger.debug(message)
+}
+
+// MaxMemoryUsageForUser sets maximum memory usage of all simultaneous queries of the user (limit in bytes)
+// Zero limit returns the server default
+func (conn *Conn) MaxMemoryUsageForUser(limit int) {
+	if limit < 0 {
+		return
+	}
+
+	if limit == 0 {
+		conn.Setting("max_memory_usage_for_user", "")
+
+		return
+	}
+
+	conn.Setting("max_memory_usage_for_user", strconv.Itoa(limit))
+}
+
+// MaxBytesBeforeExternalGroupBy sets amount of memory of GROUP BY after which data is spilled to disk (limit in bytes)
+// so heavy aggregations don't exceed memory limits (usually a half of max_memory_usage)
+// Zero limit turns external aggregation off
+func (conn *Conn) MaxBytesBeforeExternalGroupBy(limit int) {
+	if limit < 0 {
+		return
+	}
+
+	if limit == 0 {
+		conn.Setting("max_bytes_before_external_group_by", "")
+
+		return
+	}
+
+	conn.Setting("max_bytes_before_external_group_by", strconv.Itoa(limit))
+}
+
+// ConnectTimeout sets new connection timeout
+func (conn *Conn) ConnectTimeout(timeout int) {
+	if timeout < 0 {
+		return
+	}
+
+	atomic.StoreInt32(&conn.connectTimeout, int32(timeout))
+
+	message := fmt.Sprintf("Set connect_timeout = %d s", timeout)
+	cfg.logger.debug(message)
+}
+
+// SendTimeout sets new send timeout
+func (conn *Conn) SendTimeout(timeout int) {
+	if timeout < 0 {
+		return
+	}
+
+	atomic.StoreInt32(&conn.sendTimeout, int32(timeout))
+
+	message := fmt.Sprintf("Set send_timeout = %d s", timeout)
+	cfg.logger.debug(message)
+}
+
+// Compression sets new send timeout
+func (conn *Conn) Compression(compression bool) {
+	var compInt int32 = 0
+	if compression {
+		compInt = 1
+	}
+
+	atomic.StoreInt32(&conn.compression, compInt)
+
+	message := fmt.Sprintf("Set compression = %d", conn.compression)
+	cfg.logger.debug(message)
+}
+
+// CompressionLevel turns on gzip compression of request bodies with passed level
+// The level has to be between gzip.HuffmanOnly (-2) and gzip.BestCompression (9)
+func (conn *Conn) CompressionLevel(level int) error {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return fmt.Errorf("compression level %d is out of range [%d, %d]", level, gzip.HuffmanOnly, gzip.BestCompression)
+	}
+
+	atomic.StoreInt32(&conn.bodyLevel, int32(level))
+	atomic.StoreInt32(&conn.bodyCompress, 1)
+
+	message := fmt.Sprintf("Set request compression level = %d", level)
+	cfg.logger.debug(message)
+
+	return nil
+}
+
+// ResponseCompressionLevel sets level of compression of responses (http_zlib_compression_level setting)
+// The level has to be between 1 and 9, lower level spends less CPU of server on compression of small responses
+func (conn *Conn) ResponseCompressionLevel(level int) error {
+	if level < 1 || level > 9 {
+		return fmt.Errorf("response compression level %d is out of range [1, 9]", level)
+	}
+
+	conn.Setting("http_zlib_compression_level", strconv.Itoa(level))
+
+	return nil
+}
+
+// ReceiveTimeout sets new receive timeout
+// Sum of connect, send and receive timeouts limits every attempt of query
+// while deadline of context of query (e.g. of InsertBatchContext) limits all attempts
+// so the tightest of them is applied
+func (conn *Conn) ReceiveTimeout(timeout int) {
+	atomic.StoreInt32(&conn.receiveTimeout, int32(timeout))
+
+	message := fmt.Sprintf("Set receive_timeout = %d s", timeout)
+	cfg.logger.debug(message)
+}
+
+// Proxy sets URL of HTTP proxy the queries are sent through
+// Empty URL restores proxy from HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+// The transport is shared with clones of the connection so they get the setting too
+func (conn *Conn) Proxy(proxyURL string) error {
+	if len(proxyURL) == 0 {
+		conn.transport.update(func(transport *http.Transport) {
+			transport.Proxy = http.ProxyFromEnvironment
+		})
+
+		cfg.logger.debug("Set proxy from environment")
+
+		return nil
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		err = fmt.Errorf("can't parse proxy url: %s", err.Error())
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return err
+	}
+
+	conn.transport.update(func(transport *http.Transport) {
+		transport.Proxy = http.ProxyURL(u)
+	})
+
+	message := fmt.Sprintf("Set proxy = %s://%s", u.Scheme, u.Host)
+	cfg.logger.debug(message)
+
+	return nil
+}
+
+// IdleTimeout sets time while idle connection is kept for reuse (zero means there is no limitation)
+// It has to be less than idle timeout of server and load balancers so stale connections aren't reused
+// The transport is shared with clones of the connection so they get the setting too
+func (conn *Conn) IdleTimeout(timeout time.Duration) {
+	conn.transport.update(func(transport *http.Transport) {
+		transport.IdleConnTimeout = timeout
+	})
+
+	message := fmt.Sprintf("Set idle connection timeout = %s", timeout)
+	cfg.logger.debug(message)
+}
+
+// DisableKeepAlives turns off reusing of connections between queries (keep-alives are enabled by default)
+// The transport is shared with clones of the connection so they get the setting too
+func (conn *Conn) DisableKeepAlives(flag bool) {
+	var keepAlive int32 = 1
+	if flag {
+		keepAlive = 0
+	}
+
+	conn.transport.update(func(transport *http.Transport) {
+		transport.DisableKeepAlives = flag
+	})
+	atomic.StoreInt32(&conn.keepAlive, keepAlive)
+
+	message := fmt.Sprintf("Set keep-alive = %d", keepAlive)
+	cfg.logger.debug(message)
+}
+
+// SetQueryInterceptor sets function called with every query before sending (nil function removes the interceptor)
+// The query returned by the function is sent instead of the original one and error of the function aborts the query
+// It's useful for common policies e.g. forbidding of DROP queries or adding of mandatory LIMIT
+func (conn *Conn) SetQueryInterceptor(interceptor func(query string) (string, error)) {
+	conn.mux.Lock()
+	conn.interceptor = interceptor
+	conn.mux.Unlock()
+
+	if interceptor == nil {
+		cfg.logger.debug("Remove query interceptor")
+	} else {
+		cfg.logger.debug("Set query interceptor")
+	}
+}
+
+// MaxQueryBytes sets maximum size of query text which is checked before sending (zero removes the check)
+// Data streamed by inserts isn't a part of query text so it isn't limited
+func (conn *Conn) MaxQueryBytes(limit int) {
+	if limit < 0 {
+		return
+	}
+
+	atomic.StoreUint32(&conn.maxQueryBytes, uint32(limit))
+
+	message := fmt.Sprintf("Set max query size = %d bytes", limit)
+	cfg.logger.debug(message)
+}
+
+// Close stops requests limiter and closes idle connections
+// Resources are shared with clones of the connection so they become closed too
+func (conn *Conn) Close() error {
+	conn.Limiter.close()
+	conn.transport.get().CloseIdleConnections()
+
+	cfg.logger.debug("The connection is closed")
+
+	return nil
+}
+
+// WaitEndOfQuery makes server buffer response of INSERT queries until the end of the query
+// so insert errors are returned with error status instead of failure after 200 response
+// It doesn't enable send_progress_in_http_headers because progress headers make server send the status earlier
+func (conn *Conn) WaitEndOfQuery(flag bool) {
+	var flagInt int32 = 0
+	if flag {
+		flagInt = 1
+	}
+
+	atomic.StoreInt32(&conn.waitEndOfQuery, flagInt)
+
+	message := fmt.Sprintf("Set wait_end_of_query for inserts = %d", flagInt)
+	cfg.logger.debug(message)
+}
+
+// BestEffortDates makes server parse DateTime values of INSERT queries in wide range of formats
+// (date_time_input_format=best_effort) e.g. ISO 8601 with time zone
+func (conn *Conn) BestEffortDates(flag bool) {
+	var flagInt int32 = 0
+	if flag {
+		flagInt = 1
+	}
+
+	atomic.StoreInt32(&conn.bestEffort, flagInt)
+
+	message := fmt.Sprintf("Set best effort dates parsing for inserts = %d", flagInt)
+	cfg.logger.debug(message)
+}
+
+// AutoDedup makes batch inserts send SHA-256 hash of data as insert_deduplication_token
+// so identical retried batches aren't inserted twice into Replicated*MergeTree table
+// Streamed data (InsertBatchContext) is hashed only if the reader is io.ReadSeeker because it's read twice
+// Explicit DeduplicationToken of insert options takes precedence over the hash
+func (conn *Conn) AutoDedup(flag bool) {
+	var flagInt int32 = 0
+	if flag {
+		flagInt = 1
+	}
+
+	atomic.StoreInt32(&conn.autoDedup, flagInt)
+
+	message := fmt.Sprintf("Set automatic deduplication token of inserts = %d", flagInt)
+	cfg.logger.debug(message)
+}
+
+// UseGet sends read-only queries (SELECT, SHOW etc.) with GET method if URL length fits maxLength
+// Longer queries and all the rest are sent with POST method (zero turns GET off)
+func (conn *Conn) UseGet(maxLength int) {
+	if maxLength < 0 {
+		return
+	}
+
+	atomic.StoreUint32(&conn.getMaxLength, uint32(maxLength))
+
+	message := fmt.Sprintf("Set GET method max URL length = %d", maxLength)
+	cfg.logger.debug(message)
+}
+
+// Exec executes new query
+func (conn *Conn) Exec(query string) error {
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	return conn.ForcedExec(query)
+}
+
+// ForcedExec executes new query without requests limits
+func (conn *Conn) ForcedExec(query string) error {
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	_, err := conn.exec(context.Background(), query, nil)
+
+	return err
+}
+
+// QueryOptions describes optional behaviour of single query
+// Zero values keep settings of connection
+type QueryOptions struct {
+	// Attempts overrides amount of attempts of connection (see Attempts) e.g. 1 turns off retries of non-idempotent query
+	Attempts int
+	// AttemptWait overrides wait between attempts in seconds (negative wait retries without waiting)
+	AttemptWait int
+}
+
+// ExecWithOptions executes new query with query options and returns its summary
+func (conn *Conn) ExecWithOptions(ctx context.Context, query string, options QueryOptions) (ExecResult, error) {
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	return conn.exec(withAttempts(ctx, options), query, nil)
+}
+
+// ExecFetch executes new statement as is and fetches rows it returns (e.g. INSERT ... SELECT or ALTER with output)
+// FORMAT clause of the statement is kept and the default format is used if there is no such clause
+// The iterator has no rows if the statement doesn't return anything
+func (conn *Conn) ExecFetch(query string) (Iter, error) {
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	ctx := context.Background()
+
+	format, ok := getFormat(query)
+	if !ok {
+		format = conn.fetchFormat(query)
+
+		// not every statement allows FORMAT clause so format of output is set by the setting
+		ctx = WithSetting(ctx, "default_format", string(format))
+	}
+
+	return conn.openIter(ctx, query, format, nil)
+}
+
+func (conn *Conn) exec(ctx context.Context, query string, body io.Reader) (ExecResult, error) {
+	reader, header, err := conn.doQuery(ctx, query, body)
+	if err != nil {
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return ExecResult{}, err
+	}
+
+	defer reader.Close()
+
+	// response isn't used so it's drained without accumulating
+	_, err = io.Copy(io.Discard, reader)
+	if err != nil {
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return ExecResult{}, err
+	}
+
+	message := fmt.Sprintf("The query is executed %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	result := parseSummary(header)
+	result.QueryID = header.Get("X-ClickHouse-Query-Id")
+
+	if insertRe.MatchString(query) {
+		conn.mux.Lock()
+		conn.lastInsertID = result.QueryID
+		conn.mux.Unlock()
+	}
+
+	return result, nil
+}
+
+// InsertOptions describes optional behaviour of batch inserting
+type InsertOptions struct {
+	// InputTypes are types of columns (in the same order)
+	// If they are set data is inserted through input() table function
+	// so defaults and materialized expressions of the rest columns are applied by server
+	InputTypes []string
+	// OnInsertProgress is called with amount of sent bytes of data every time HTTP client reads next chunk
+	// It's called from the goroutine which sends the request and starts from zero if the insert is retried
+	OnInsertProgress func(bytesSent int64)
+	// Settings are sent only with the insert (e.g. format_csv_delimiter or input_format_skip_unknown_fields)
+	// They override connection settings and settings of context
+	Settings map[string]string
+	// DeduplicationToken is sent as insert_deduplication_token setting
+	// so the batch which is retried after failure isn't inserted twice into Replicated*MergeTree table
+	DeduplicationToken string
+	// RecordSeparator separates rows of data passed into InsertBatchWithOptions (line break by default)
+	// Rows separated by another byte (e.g. \b) are sent as lines and their trailing line breaks are trimmed
+	RecordSeparator byte
+	// ContentEncoding is encoding of already compressed data (e.g. lz4 or zstd)
+	// The data is sent as is with Content-Encoding header so it isn't compressed again or split into rows
+	ContentEncoding string
+	// ContentType is sent as Content-Type header of the insert instead of text/plain
+	ContentType string
+}
+
+// contentEncodings are encodings of request body supported by server
+var contentEncodings = map[string]bool{
+	"gzip": true, "deflate": true, "br": true, "xz": true, "zstd": true, "lz4": true, "bz2": true, "snappy": true}
+
+// InsertBatch inserts TSV data into `database.table` table
+func (conn *Conn) InsertBatch(database, table string, columns []string, format Format, tsvReader io.Reader) error {
+	return conn.InsertBatchWithOptions(database, table, columns, format, tsvReader, InsertOptions{})
+}
+
+// InsertBatchWithOptions inserts TSV data into `database.table` table with insert options
+func (conn *Conn) InsertBatchWithOptions(database, table string, columns []string, format Format, tsvReader io.Reader, options InsertOptions) error {
+	if format == RowBinary {
+		// binary rows can't be split by lines so they're streamed as is
+		return conn.InsertBatchContext(context.Background(), database, table, columns, format, tsvReader, options)
+	}
+
+	query, err := conn.batchQuery(database, table, columns, format, options)
+	if err != nil {
+		return err
+	}
+
+	if len(options.ContentEncoding) > 0 {
+		// compressed data can't be split into rows so it's streamed as is
+		return conn.sendBatch(context.Background(), query, tsvReader, options)
+	}
+
+	reader := bufio.NewReader(tsvReader)
+
+	separator := options.RecordSeparator
+	if separator == 0 {
+		separator = '\n'
+	}
+
+	var (
+		bs      []byte
+		builder strings.Builder
+	)
+
+	for {
+		bs, err = reader.ReadBytes(separator)
+
+		if separator == '\n' {
+			builder.Write(bs)
+		} else if record := strings.TrimRight(strings.TrimSuffix(string(bs), string(separator)), "\r\n"); len(record) > 0 {
+			// records separated by custom separator are forwarded as lines
+			builder.WriteString(record)
+			builder.WriteByte('\n')
+		}
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	body := normalizeBatch(builder.String())
+	if len(body) == 0 {
+		cfg.logger.debug("There is no data to insert")
+
+		return nil
+	}
+
+	return conn.sendBatch(context.Background(), query, strings.NewReader(body+"\n"), options)
+}
+
+// InsertBatchContext streams data into `database.table` table without buffering of it in memory
+// Unlike InsertBatch the data is sent as is so it mustn't contain trailing terminators
+// The insert is aborted if context is cancelled
+func (conn *Conn) InsertBatchContext(ctx context.Context, database, table string, columns []string, format Format, reader io.Reader, options InsertOptions) error {
+	query, err := conn.batchQuery(database, table, columns, format, options)
+	if err != nil {
+		return err
+	}
+
+	buffered := bufio.NewReader(reader)
+	if _, err = buffered.Peek(1); err == io.EOF {
+		cfg.logger.debug("There is no data to insert")
+
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var body io.Reader = buffered
+	if source, ok := reader.(io.ReadSeeker); ok {
+		// keeps possibility to retry the insert
+		body = rewindReader{Reader: buffered, source: source}
+	}
+
+	return conn.sendBatch(ctx, query, body, options)
+}
+
+// Batch describes data inserted by InsertBatches
+type Batch struct {
+	Database string
+	Table    string
+	Columns  []string
+	Format   Format
+	Reader   io.Reader
+	Options  InsertOptions
+}
+
+// InsertBatches inserts batches one by one with InsertBatchContext and stops on the first failed batch
+// The batches aren't inserted atomically (inserted batches stay if the next one fails) unless server transactions are on
+func (conn *Conn) InsertBatches(ctx context.Context, batches []Batch) error {
+	for index, batch := range batches {
+		err := conn.InsertBatchContext(ctx, batch.Database, batch.Table, batch.Columns, batch.Format, batch.Reader, batch.Options)
+		if err != nil {
+			err = fmt.Errorf("can't insert batch %d into %s.%s (%d batches are inserted): %w", index, batch.Database, batch.Table, index, err)
+
+			message := fmt.Sprintf("Catch error %s", err.Error())
+			cfg.logger.error(message)
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Pipe streams result of select query in TabSeparated format into `destDatabase.destTable` table
+// The rows aren't materialized in memory and both queries are cancelled if one of them fails
+// Columns of the table are set in order of the select query columns (all columns of the table if there are no columns)
+func (conn *Conn) Pipe(ctx context.Context, selectQuery, destDatabase, destTable string, columns []string) error {
+	query, err := conn.batchQuery(destDatabase, destTable, columns, TSV, InsertOptions{})
+	if err != nil {
+		return err
+	}
+
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	selectQuery = setFormat(selectQuery, TSV)
+
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(selectQuery, 500))
+	cfg.logger.debug(message)
+
+	// response of the select query is sent as data of the insert so closing of it cancels the select query
+	source, _, err := conn.doQuery(ctx, selectQuery, nil)
+	if err != nil {
+		return err
+	}
+
+	defer source.Close()
+
+	message = fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	_, err = conn.exec(ctx, query, source)
+
+	return err
+}
+
+func (conn *Conn) batchQuery(database, table string, columns []string, format Format, options InsertOptions) (string, error) {
+	err := validateBatch(columns, format)
+	if err == nil && len(options.InputTypes) > 0 && len(options.InputTypes) != len(columns) {
+		err = fmt.Errorf("there are %d input types for %d columns", len(options.InputTypes), len(columns))
+	}
+
+	if err == nil && len(options.ContentEncoding) > 0 && !contentEncodings[options.ContentEncoding] {
+		err = fmt.Errorf("content encoding `%s` isn't supported", options.ContentEncoding)
+	}
+
+	if err == nil && len(columns) > 0 && atomic.LoadInt32(&conn.checkInserts) == 1 {
+		err = conn.validateInsert(database, table, columns)
+	}
+
+	if err != nil {
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return "", err
+	}
+
+	columns = conn.quoteColumns(columns)
+
+	if len(options.InputTypes) > 0 {
+		structure := make([]string, 0, len(columns))
+		for index, column := range columns {
+			structure = append(structure, column+" "+options.InputTypes[index])
+		}
+
+		return fmt.Sprintf("INSERT INTO %s.%s (%s) SELECT %s FROM input('%s') FORMAT %s", database, table,
+			strings.Join(columns, ", "), strings.Join(columns, ", "), escapeValuesString(strings.Join(structure, ", ")), format), nil
+	} else if len(columns) == 0 {
+		return fmt.Sprintf("INSERT INTO %s.%s FORMAT %s", database, table, format), nil
+	}
+
+	return fmt.Sprintf("INSERT INTO %s.%s (%s) FORMAT %s", database, table, strings.Join(columns, ", "), format), nil
+}
+
+func (conn *Conn) sendBatch(ctx context.Context, query string, body io.Reader, options InsertOptions) error {
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	if len(options.DeduplicationToken) == 0 && atomic.LoadInt32(&conn.autoDedup) == 1 {
+		var err error
+		options.DeduplicationToken, err = hashBody(body)
+		if err != nil {
+			message := fmt.Sprintf("Catch error %s", err.Error())
+			cfg.logger.error(message)
+
+			return err
+		}
+	}
+
+	if options.OnInsertProgress != nil {
+		body = newProgressReader(body, options.OnInsertProgress)
+	}
+
+	if len(options.Settings) > 0 {
+		ctx = WithSettings(ctx, options.Settings)
+	}
+
+	if len(options.DeduplicationToken) > 0 {
+		ctx = WithSetting(ctx, "insert_deduplication_token", options.DeduplicationToken)
+	}
+
+	if len(options.ContentEncoding) > 0 {
+		ctx = withContentEncoding(ctx, options.ContentEncoding)
+	}
+
+	if len(options.ContentType) > 0 {
+		ctx = withContentType(ctx, options.ContentType)
+	}
+
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	_, err := conn.exec(ctx, query, body)
+
+	return err
+}
+
+// hashBody returns hex of SHA-256 hash of body and rewinds it or empty string if body can't be rewound
+func hashBody(body io.Reader) (string, error) {
+	seeker, ok := body.(io.ReadSeeker)
+	if !ok {
+		cfg.logger.debug("Skip deduplication token of streamed data which can't be rewound")
+
+		return "", nil
+	}
+
+	hash := sha256.New()
+
+	_, err := io.Copy(hash, seeker)
+	if err != nil {
+		return "", fmt.Errorf("can't hash data to insert: %s", err.Error())
+	}
+
+	_, err = seeker.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", fmt.Errorf("can't rewind data to insert after hashing: %s", err.Error())
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// normalizeBatch trims redundant leading line breaks and trailing terminators
+// (line breaks, \b and standalone semicolon line) of batch data
+func normalizeBatch(body string) string {
+	body = strings.TrimLeft(body, "\r\n")
+
+	for {
+		trimmed := strings.TrimRight(body, "\r\n\b")
+		if trimmed == ";" {
+			trimmed = ""
+		} else if strings.HasSuffix(trimmed, "\n;") {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+
+		if trimmed == body {
+			return body
+		}
+
+		body = trimmed
+	}
+}
+
+// quoteColumns quotes names of columns with backticks if quoting is on (already quoted names are kept as is)
+func (conn *Conn) quoteColumns(columns []string) []string {
+	if atomic.LoadInt32(&conn.quoteIdents) != 1 {
+		return columns
+	}
+
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if len(column) > 1 && column[0] == '`' && column[len(column)-1] == '`' {
+			quoted = append(quoted, column)
+		} else {
+			quoted = append(quoted, QuoteIdent(column))
+		}
+	}
+
+	return quoted
+}
+
+var columnRe = regexp.MustCompile("^([A-Za-z_][A-Za-z0-9_.]*|`([^`\\\\]|\\\\.)+`)$")
+
+func validateBatch(columns []string, format Format) error {
+	switch format {
+	case TSV, TSVWithNames, CSV, CSVWithNames, JSONEachRow, RowBinary:
+	default:
+		return fmt.Errorf("format `%s` can't be used to insert batch (use InsertFile instead)", format)
+	}
+
+	for index, column := range columns {
+		if len(column) == 0 {
+			return fmt.Errorf("column %d has empty name", index)
+		}
+
+		if !columnRe.MatchString(column) {
+			return fmt.Errorf("column %d has invalid name `%s`", index, column)
+		}
+	}
+
+	return nil
+}
+
+// validateInsert checks that columns exist in `database.table` table and can be inserted
+// All problems are listed in the single error
+func (conn *Conn) validateInsert(database, table string, columns []string) error {
+	query := fmt.Sprintf("DESCRIBE TABLE %s.%s FORMAT TabSeparatedWithNames", database, table)
+
+	kinds := map[string]string{}
+	err := conn.eachRow(query, func(result Result) (err error) {
+		var name, kind string
+
+		if name, err = result.String("name"); err != nil {
+			return
+		}
+
+		if kind, err = result.String("default_type"); err != nil {
+			return
+		}
+
+		kinds[name] = kind
+
+		return
+	})
+	if err != nil {
+		return fmt.Errorf("can't describe table %s.%s: %w", database, table, err)
+	}
+
+	var problems []string
+	for _, column := range columns {
+		name := unquoteIdent(column)
+
+		kind, ok := kinds[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("column `%s` doesn't exist", name))
+		} else if kind == "MATERIALIZED" || kind == "ALIAS" {
+			problems = append(problems, fmt.Sprintf("column `%s` is %s", name, kind))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("can't insert into %s.%s: %s", database, table, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// unquoteIdent undoes quoting of identifier with backticks (unquoted identifier is returned as is)
+func unquoteIdent(name string) string {
+	if len(name) < 2 || name[0] != '`' || name[len(name)-1] != '`' {
+		return name
+	}
+
+	var builder strings.Builder
+
+	name = name[1 : len(name)-1]
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+1 < len(name) {
+			i++
+		}
+
+		builder.WriteByte(name[i])
+	}
+
+	return builder.String()
+}
+
+// InsertFile streams pre-formatted data (e.g. Native or Parquet dump) into `database.table` table
+// The data is sent as is so it must be encoded with passed format
+func (conn *Conn) InsertFile(ctx context.Context, database, table string, format Format, file io.Reader) error {
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	query := fmt.Sprintf("INSERT INTO %s.%s FORMAT %s", database, table, format)
+
+	message := fmt.Sprintf("Try to execute: %s", query)
+	cfg.logger.debug(message)
+
+	_, err := conn.exec(ctx, query, file)
+
+	return err
+}
+
+// InsertValues inserts rows into `database.table` table with VALUES format
+func (conn *Conn) InsertValues(database, table string, columns []string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		cfg.logger.debug("There are no rows to insert")
+
+		return nil
+	}
+
+	var query string
+	if len(columns) == 0 {
+		query = fmt.Sprintf("INSERT INTO %s.%s VALUES ", database, table)
+	} else {
+		query = fmt.Sprintf("INSERT INTO %s.%s (%s) VALUES ", database, table, strings.Join(conn.quoteColumns(columns), ", "))
+	}
+
+	values := make([]string, 0, len(rows))
+	for index, row := range rows {
+		if len(columns) > 0 && len(row) != len(columns) {
+			return fmt.Errorf("row %d has %d values but %d columns are expected", index, len(row), len(columns))
+		}
+
+		fields := make([]string, 0, len(row))
+		for _, value := range row {
+			field, err := quoteValue(value)
+			if err != nil {
+				return fmt.Errorf("can't quote value of row %d: %s", index, err.Error())
+			}
+
+			fields = append(fields, field)
+		}
+
+		values = append(values, "("+strings.Join(fields, ", ")+")")
+	}
+
+	query += strings.Join(values, ", ")
+
+	return conn.Exec(query)
+}
+
+// Fetch executes new query and fetches all data
+func (conn *Conn) Fetch(query string) (Iter, error) {
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	return conn.ForcedFetch(query)
+}
+
+// FetchWithOptions executes new query with query options and fetches all data
+func (conn *Conn) FetchWithOptions(ctx context.Context, query string, options QueryOptions) (Iter, error) {
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	return conn.fetch(withAttempts(ctx, options), query)
+}
+
+// FetchLimited executes new query and fetches up to maxRows rows
+// The iterator stops without error and closes the stream after maxRows rows (zero means there is no limitation)
+func (conn *Conn) FetchLimited(query string, maxRows int) (Iter, error) {
+	iter, err := conn.Fetch(query)
+	iter.maxRows = maxRows
+
+	return iter, err
+}
+
+// ForcedFetch executes new query and fetches all data without requests limits
+func (conn *Conn) ForcedFetch(query string) (Iter, error) {
+	return conn.fetch(context.Background(), query)
+}
+
+func (conn *Conn) fetch(ctx context.Context, query string) (Iter, error) {
+	if limit := atomic.LoadUint32(&conn.defaultLimit); limit > 0 {
+		if limited, ok := injectLimit(query, int(limit)); ok {
+			message := fmt.Sprintf("Add default limit %d to query", limit)
+			cfg.logger.debug(message)
+
+			query = limited
+		}
+	}
+
+	return conn.fetchUnlimited(ctx, query)
+}
+
+// fetchUnlimited fetches query without default limit (e.g. for queries of system tables by the library)
+func (conn *Conn) fetchUnlimited(ctx context.Context, query string) (Iter, error) {
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	format := conn.fetchFormat(query)
+
+	return conn.openIter(ctx, setFormat(query, format), format, nil)
+}
+
+// fetchFormat returns format of the query or default format if the query doesn't have FORMAT clause
+func (conn *Conn) fetchFormat(query string) Format {
+	format, ok := getFormat(query)
+	if !ok {
+		conn.mux.Lock()
+		format = conn.defaultFormat
+		conn.mux.Unlock()
+	}
+
+	return format
+}
+
+// openIter executes query as is (with body if it isn't nil) and opens iterator to read response in passed format
+func (conn *Conn) openIter(ctx context.Context, query string, format Format, body io.Reader) (Iter, error) {
+	iter := Iter{conn: conn}
+
+	err := iter.open(ctx, query, format, body)
+
+	return iter, err
+}
+
+// Reset closes current stream of iterator and executes new query on the same connection
+// Buffer of the iterator is reused for the new stream
+func (iter *Iter) Reset(query string) error {
+	iter.Close()
+
+	if iter.conn == nil {
+		return errors.New("iterator doesn't have connection")
+	}
+
+	iter.conn.waitForRest()
+	iter.conn.increase()
+	defer iter.conn.reduce()
+
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	format := iter.conn.fetchFormat(query)
+
+	return iter.open(context.Background(), setFormat(query, format), format, nil)
+}
+
+func (iter *Iter) open(ctx context.Context, query string, format Format, body io.Reader) error {
+	if isMultiStatement(query) {
+		err := errors.New("multi-statement query can't be fetched with iterator (fetch every statement separately)")
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return err
+	}
+
+	iter.query = query
+	iter.format = format
+	iter.decoder = nil
+	iter.readCloser = nil
+	iter.err = nil
+	iter.Result = Result{}
+	iter.skipped = 0
+	iter.rows = 0
+	iter.location = nil
+	iter.queryID = ""
+	iter.rowsHint = 0
+	iter.index = nil
+	iter.nullRepr = ""
+	iter.isClosed = false
+
+	factory, ok := getDecoder(format)
+	if !ok {
+		err := fmt.Errorf("format `%s` can't be fetched with iterator (use FetchToWriter instead)", format)
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return err
+	}
+
+	if format == TSVWithNames || format == TSVWithNamesAndTypes {
+		iter.nullRepr = iter.conn.nullRepresentation(ctx)
+	}
+
+	// server splits fields by the delimiter of the query settings so the decoder has to follow it
+	if delimiter := iter.conn.csvDelimiter(ctx); format == CSVWithNames && delimiter != ',' {
+		factory = csvDecoderFactory(delimiter)
+	}
+
+	var (
+		header http.Header
+		err    error
+	)
+
+	iter.readCloser, header, err = iter.conn.doQuery(ctx, query, body)
+
+	if err != nil {
+		return err
+	}
+
+	iter.location = parseTimezone(header)
+	iter.queryID = header.Get("X-ClickHouse-Query-Id")
+	iter.rowsHint = parseSummary(header).TotalRowsToRead
+
+	cfg.logger.debug("Open stream to fetch")
+
+	if iter.buffer == nil {
+		iter.buffer = bufio.NewReaderSize(iter.readCloser, iter.conn.getBufferSize())
+	} else {
+		iter.buffer.Reset(iter.readCloser)
+	}
+
+	if _, err = iter.buffer.Peek(1); err == io.EOF {
+		// statements without output (e.g. via ExecFetch) have empty response so there are no rows
+		iter.readCloser.Close()
+		iter.isClosed = true
+
+		cfg.logger.debug("There is no data to fetch")
+
+		return nil
+	}
+
+	iter.decoder, err = factory(iter.buffer)
+	if err != nil {
+		iter.readCloser.Close()
+		iter.isClosed = true
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.fatal(message)
+
+		return err
+	}
+
+	cfg.logger.debug("Load fields names")
+
+	return nil
+}
+
+func isIterable(format Format) bool {
+	_, ok := getDecoder(format)
+
+	return ok
+}
+
+// FetchToWriter executes new query and copies raw response in passed format to writer
+// It returns amount of written bytes
+func (conn *Conn) FetchToWriter(ctx context.Context, query string, format Format, w io.Writer) (int64, error) {
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	reader, _, err := conn.doQuery(ctx, setFormat(query, format), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer reader.Close()
+
+	written, err := io.Copy(w, reader)
+	if err != nil {
+		message = fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return written, err
+	}
+
+	message = fmt.Sprintf("The query is fetched: %d bytes are written", written)
+	cfg.logger.debug(message)
+
+	return written, nil
+}
+
+var (
+	formatRe    = regexp.MustCompile(`(?i)(\s+FORMAT\s+[A-Za-z0-9]+)?\s*;?\s*$`)
+	getFormatRe = regexp.MustCompile(`(?i)FORMAT\s+([A-Za-z0-9]+)\s*;?\s*$`)
+)
+
+func setFormat(query string, format Format) string {
+	return formatRe.ReplaceAllString(query, " FORMAT "+string(format))
+}
+
+// isMultiStatement checks if query has several statements separated by semicolons
+// Semicolons inside quoted strings, identifiers and comments and the trailing semicolon are ignored
+func isMultiStatement(query string) bool {
+	var (
+		quote     byte
+		separated bool
+	)
+
+	for i := 0; i < len(query); i++ {
+		char := query[i]
+
+		switch {
+		case quote != 0:
+			if char == '\\' {
+				i++
+			} else if char == quote {
+				quote = 0
+			}
+
+			continue
+		case char == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return false
+			}
+
+			i += end
+
+			continue
+		case char == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i:], "*/")
+			if end < 0 {
+				return false
+			}
+
+			i += end + 1
+
+			continue
+		}
+
+		switch char {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case ';':
+			separated = true
+
+			continue
+		}
+
+		if separated {
+			return true
+		}
+
+		if char == '\'' || char == '"' || char == '`' {
+			quote = char
+		}
+	}
+
+	return false
+}
+
+// getFormat returns format specified in the query
+func getFormat(query string) (Format, bool) {
+	matches := getFormatRe.FindStringSubmatch(query)
+	if len(matches) < 2 {
+		return "", false
+	}
+
+	format := Format(matches[1])
+	switch format {
+	case "TSVWithNames":
+		format = TSVWithNames
+	case "TSVWithNamesAndTypes":
+		format = TSVWithNamesAndTypes
+	}
+
+	return format, true
+}
+
+// FetchOne executes new query and fetches one row
+func (conn *Conn) FetchOne(query string) (Result, error) {
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	return conn.ForcedFetchOne(query)
+}
+
+// ForcedFetchOne executes new query and fetches one row without requests limits
+func (conn *Conn) ForcedFetchOne(query string) (Result, error) {
+	iter, err := conn.ForcedFetch(query)
+	if err != nil {
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return Result{}, err
+	}
+
+	defer iter.Close()
+
+	if iter.Next() {
+		return iter.Result, nil
+	}
+
+	return Result{}, nil
+}
+
+// FetchExactlyOne executes new query and fetches the only row
+// It returns ErrNoRows if there are no rows and ErrMultipleRows if there are more than one row
+func (conn *Conn) FetchExactlyOne(query string) (Result, error) {
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	iter, err := conn.ForcedFetch(query)
+	if err != nil {
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return Result{}, err
+	}
+
+	defer iter.Close()
+
+	if !iter.Next() {
+		if err = iter.Err(); err != nil {
+			return Result{}, err
+		}
+
+		return Result{}, ErrNoRows
+	}
+
+	result := iter.Result
+
+	// the second row is read to check uniqueness and the rest rows are dropped by closing of the stream
+	if iter.Next() {
+		err = fmt.Errorf("%w: %s", ErrMultipleRows, cutOffQuery(query, 100))
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return Result{}, err
+	}
+
+	if err = iter.Err(); err != nil {
+		return Result{}, err
+	}
+
+	return result, nil
+}
+
+// Each executes new query and calls fn for every row
+// It stops on the first error of fn or of iteration and always closes the stream
+// If bad rows skipping is on, errors of fn are logged as warnings and counted as skipped rows
+func (conn *Conn) Each(query string, fn func(result Result) error) (skipped int, err error) {
+	iter, err := conn.Fetch(query)
+	if err != nil {
+		return 0, err
+	}
+
+	defer iter.Close()
+
+	for iter.Next() {
+		err = fn(iter.Result)
+		if err != nil {
+			if !conn.isSkippingBadRows() {
+				return iter.Skipped() + skipped, err
+			}
+
+			skipped++
+
+			message := fmt.Sprintf("Catch warning skip row: %s", err.Error())
+			cfg.logger.warn(message)
+		}
+	}
+
+	return iter.Skipped() + skipped, iter.Err()
+}
+
+// FetchAll executes new query and returns all rows
+// If bad rows skipping is on, malformed rows are skipped and counted
+func (conn *Conn) FetchAll(query string) (results []Result, skipped int, err error) {
+	iter, err := conn.Fetch(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer iter.Close()
+
+	for iter.Next() {
+		if results == nil {
+			results = make([]Result, 0, iter.rowsCapacity())
+		}
+
+		results = append(results, iter.Result)
+	}
+
+	return results, iter.Skipped(), iter.Err()
+}
+
+// totalColumn is column with total amount of rows of query added by FetchWithCount
+const totalColumn = "__total"
+
+// FetchWithCount executes new query and returns rows of page (starting with 0) and total amount of rows of the query
+// The total is counted with count() OVER () window in the same query so it's zero if the page is beyond the last row
+// FORMAT clause of the query is dropped because the query is wrapped as subquery
+func (conn *Conn) FetchWithCount(query string, page, pageSize int) (rows []Result, total uint64, err error) {
+	if page < 0 || pageSize <= 0 {
+		err = fmt.Errorf("page %d of size %d is invalid", page, pageSize)
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return nil, 0, err
+	}
+
+	query = fmt.Sprintf("SELECT *, count() OVER () AS %s FROM (%s) LIMIT %d OFFSET %d",
+		totalColumn, strings.TrimSpace(formatRe.ReplaceAllString(query, "")), pageSize, page*pageSize)
+
+	iter, err := conn.Fetch(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer iter.Close()
+
+	for iter.Next() {
+		if len(rows) == 0 {
+			total, err = iter.Result.UInt64(totalColumn)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+
+		delete(iter.Result.data, totalColumn)
+		delete(iter.Result.index, strings.ToLower(totalColumn))
+
+		rows = append(rows, iter.Result)
+	}
+
+	return rows, total, iter.Err()
+}
+
+// FetchColumns executes new query and returns all values of every column in order of rows
+// Values which are absent in some rows (possible with JSONEachRow) are filled with \N
+func (conn *Conn) FetchColumns(query string) (map[string][]string, error) {
+	iter, err := conn.Fetch(query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	columns := map[string][]string{}
+
+	rows := 0
+	for iter.Next() {
+		for _, column := range iter.ColumnNames() {
+			values := columns[column]
+			if values == nil {
+				values = make([]string, 0, iter.rowsCapacity())
+			}
+
+			for len(values) < rows {
+				values = append(values, `\N`)
+			}
+
+			value, ok := iter.Result.data[column]
+			if !ok {
+				value = `\N`
+			}
+
+			columns[column] = append(values, value)
+		}
+
+		rows++
+	}
+
+	if err = iter.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, column := range iter.ColumnNames() {
+		if _, ok := columns[column]; !ok {
+			columns[column] = []string{}
+		}
+	}
+
+	return columns, nil
+}
+
+// Next returns next row of data
+// If bad rows skipping is on, malformed rows are logged as warnings and skipped
+func (iter *Iter) Next() bool {
+	cfg.logger.debug("Check if has more data")
+
+	if iter.isClosed || iter.decoder == nil {
+		return false
+	}
+
+	if iter.maxRows > 0 && iter.rows >= iter.maxRows {
+		message := fmt.Sprintf("Stop fetching after %d rows", iter.rows)
+		cfg.logger.info(message)
+
+		iter.Close()
+
+		return false
+	}
+
+	for {
+		data, err := iter.readRow()
+		if err == nil {
+			// custom NULL is read as \N so NULL checks of accessors don't depend on settings
+			if len(iter.nullRepr) > 0 {
+				for column, value := range data {
+					if value == iter.nullRepr {
+						data[column] = `\N`
+					}
+				}
+			}
+
+			if iter.index == nil && iter.conn != nil && atomic.LoadInt32(&iter.conn.ignoreCase) == 1 {
+				iter.index = lowerIndex(data)
+			}
+
+			iter.Result = Result{
+				conn:     iter.conn,
+				data:     data,
+				index:    iter.index,
+				types:    iter.ColumnTypes(),
+				escaped:  iter.isEscaped(),
+				location: iter.location}
+
+			iter.rows++
+
+			cfg.logger.debug("Load new data")
+
+			return true
+		}
+
+		if err == io.EOF {
+			iter.Close()
+
+			return false
+		}
+
+		if !errors.Is(err, ErrBadRow) || iter.conn == nil || !iter.conn.isSkippingBadRows() {
+			if !errors.Is(err, ErrBadRow) {
+				// the stream is broken before its end (e.g. the query is killed by server or the connection is reset)
+				err = &interruptedError{err: err}
+			}
+
+			iter.err = err
+
+			message := fmt.Sprintf("Catch error %s", err.Error())
+			cfg.logger.error(message)
+
+			return false
+		}
+
+		iter.skipped++
+
+		message := fmt.Sprintf("Catch warning skip row: %s", err.Error())
+		cfg.logger.warn(message)
+	}
+}
+
+// isEscaped checks if values of rows have TabSeparated escaping which has to be undone by accessors
+func (iter *Iter) isEscaped() bool {
+	if iter.conn != nil && iter.conn.isRawFields() {
+		return false
+	}
+
+	return iter.format == TSVWithNames || iter.format == TSVWithNamesAndTypes
+}
+
+// ReuseRows makes iterator fill map of values of the previous row instead of creating new one for every row
+// It reduces allocations of large results but Result of the previous row mustn't be kept (e.g. use Scan or copy values)
+// Decoders of TabSeparated and CSV formats support reusing while the rest formats create new map anyway
+func (iter *Iter) ReuseRows(flag bool) {
+	iter.reuseRows = flag
+}
+
+func (iter *Iter) readRow() (map[string]string, error) {
+	reuser, ok := iter.decoder.(rowReuser)
+	if !ok || !iter.reuseRows {
+		return iter.decoder.ReadRow()
+	}
+
+	data := iter.Result.data
+	if data == nil {
+		data = make(map[string]string, len(iter.decoder.Columns()))
+	}
+
+	err := reuser.ReadRowInto(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Query returns executed query with FORMAT clause set by fetching
+func (iter Iter) Query() string {
+	return iter.query
+}
+
+// Skipped returns amount of skipped bad rows
+func (iter Iter) Skipped() int {
+	return iter.skipped
+}
+
+// TSVReader returns reader which serializes remaining rows back to TabSeparated data
+// Fields are written in the order of the query columns
+func (iter *Iter) TSVReader() io.Reader {
+	return &tsvReader{
+		iter:    iter,
+		columns: iter.ColumnNames()}
+}
+
+type tsvReader struct {
+	iter    *Iter
+	columns []string
+	buf     []byte
+}
+
+func (reader *tsvReader) Read(p []byte) (int, error) {
+	for len(reader.buf) == 0 {
+		if !reader.iter.Next() {
+			if err := reader.iter.Err(); err != nil {
+				return 0, err
+			}
+
+			return 0, io.EOF
+		}
+
+		fields := make([]string, 0, len(reader.columns))
+		for _, column := range reader.columns {
+			fields = append(fields, reader.iter.Result.data[column])
+		}
+
+		reader.buf = []byte(strings.Join(fields, "\t") + "\n")
+	}
+
+	n := copy(p, reader.buf)
+	reader.buf = reader.buf[n:]
+
+	return n, nil
+}
+
+const (
+	// defaultRowsCapacity is capacity of slices collecting rows if server doesn't report amount of rows to read
+	defaultRowsCapacity = 64
+	// maxRowsCapacity limits the reported amount because rows can be filtered or aggregated by the query
+	maxRowsCapacity = 64 * 1024
+)
+
+// rowsCapacity returns capacity hint of slices collecting rows of the query
+// by amount of rows to read reported by server in X-ClickHouse-Summary header
+func (iter *Iter) rowsCapacity() int {
+	hint := iter.rowsHint
+	if hint == 0 {
+		return defaultRowsCapacity
+	}
+
+	if iter.maxRows > 0 && hint > uint64(iter.maxRows) {
+		hint = uint64(iter.maxRows)
+	}
+
+	if hint > maxRowsCapacity {
+		hint = maxRowsCapacity
+	}
+
+	return int(hint)
+}
+
+// Timezone returns time zone of the server or session reported in X-ClickHouse-Timezone header or nil if it's unknown
+// DateTime values of results are parsed in the time zone
+func (iter *Iter) Timezone() *time.Location {
+	return iter.location
+}
+
+// QueryID returns ID of the query reported by server in X-ClickHouse-Query-Id header or empty string if it's unknown
+// It's useful to find the query in system.query_log even if query_id isn't set by client
+func (iter *Iter) QueryID() string {
+	return iter.queryID
+}
+
+// parseTimezone returns location of X-ClickHouse-Timezone header or nil if the header is absent or unknown
+func parseTimezone(header http.Header) *time.Location {
+	name := header.Get("X-ClickHouse-Timezone")
+	if len(name) == 0 {
+		return nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		message := fmt.Sprintf("Catch warning can't load time zone %s: %s", name, err.Error())
+		cfg.logger.warn(message)
+
+		return nil
+	}
+
+	return location
+}
+
+// ColumnTypes returns types of columns by names
+// Types are known only if the query is fetched with TabSeparatedWithNamesAndTypes format otherwise it returns nil
+func (iter *Iter) ColumnTypes() map[string]string {
+	typed, ok := iter.decoder.(interface{ ColumnTypes() map[string]string })
+	if !ok {
+		return nil
+	}
+
+	return typed.ColumnTypes()
+}
+
+// ColumnNames returns names of columns in order of the query
+// Columns of JSONEachRow format are known after reading of rows which have them
+func (iter *Iter) ColumnNames() []string {
+	if iter.decoder == nil {
+		return nil
+	}
+
+	return iter.decoder.Columns()
+}
+
+// Values returns values of the current row in order of ColumnNames (\N for absent values)
+func (iter *Iter) Values() []string {
+	columns := iter.ColumnNames()
+
+	values := make([]string, 0, len(columns))
+	for _, column := range columns {
+		value, ok := iter.Result.data[column]
+		if !ok {
+			value = `\N`
+		}
+
+		values = append(values, value)
+	}
+
+	return values
+}
+
+// Err returns error of iterator
+func (iter Iter) Err() error {
+	return iter.err
+}
+
+// Close closes stream
+func (iter *Iter) Close() {
+	if !iter.isClosed {
+		if iter.readCloser != nil {
+			iter.readCloser.Close()
+		}
+
+		iter.isClosed = true
+
+		cfg.logger.debug("The query is fetched")
+	}
+}
+
+// String returns summary of connection configuration with masked password for debugging
+func (conn *Conn) String() string {
+	conn.mux.Lock()
+	protocol := conn.protocol
+	database := conn.database
+	hasToken := len(conn.token) > 0
+	conn.mux.Unlock()
+
+	return fmt.Sprintf("%s://%s (database = %s, token = %t, connect_timeout = %d s, send_timeout = %d s, receive_timeout = %d s, "+
+		"attempts = %d, attempt wait = %d s, compression = %d, request compression = %d)",
+		protocol, conn.getFQDN(), database, hasToken,
+		atomic.LoadInt32(&conn.connectTimeout),
+		atomic.LoadInt32(&conn.sendTimeout),
+		atomic.LoadInt32(&conn.receiveTimeout),
+		atomic.LoadUint32(&conn.attemptsAmount),
+		atomic.LoadUint32(&conn.attemptWait),
+		atomic.LoadInt32(&conn.compression),
+		atomic.LoadInt32(&conn.bodyCompress))
+}
+
+// getFQDN returns address of host with user and masked password to log it
+func (conn *Conn) getFQDN() string {
+	masked := strings.Repeat("*", len(conn.pass))
+
+	fqnd := fmt.Sprintf("%s:%s@%s:%d", conn.user, masked, conn.host, conn.port)
+
+	cfg.Do(func() {
+		message := fmt.Sprintf("Connection FQDN is %s", fqnd)
+		cfg.logger.info(message)
+	})
+
+	return fqnd
+}
+
+func (conn *Conn) doQuery(ctx context.Context, query string, body io.Reader) (io.ReadCloser, http.Header, error) {
+	conn.mux.Lock()
+	interceptor := conn.interceptor
+	conn.mux.Unlock()
+
+	if interceptor != nil {
+		rewritten, err := interceptor(query)
+		if err != nil {
+			err = fmt.Errorf("query is rejected by interceptor: %w", err)
+
+			message := fmt.Sprintf("Catch error %s", err.Error())
+			cfg.logger.error(message)
+
+			return nil, nil, err
+		}
+
+		if rewritten != query {
+			message := fmt.Sprintf("Query is rewritten by interceptor: %s", cutOffQuery(rewritten, 500))
+			cfg.logger.debug(message)
+		}
+
+		query = rewritten
+	}
+
+	// the query is tracked till its response is closed so it can be cancelled by CancelAll
+	// and the total timeout limits all attempts of the query including reading of response
+	var cancel context.CancelFunc
+	if total := atomic.LoadUint32(&conn.totalTimeout); total > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(total)*time.Millisecond)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	key := conn.inflight.add(cancel, conn.queryID(ctx))
+
+	err := conn.waitForRate(ctx)
+	if err != nil {
+		conn.inflight.done(key)
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return nil, nil, err
+	}
+
+	allowed, probe := conn.breaker.allow()
+	if !allowed {
+		conn.inflight.done(key)
+
+		err = fmt.Errorf("%w: host %s isn't available", ErrCircuitOpen, conn.getFQDN())
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return nil, nil, err
+	}
 
-	return fqnd
+	reader, header, err := conn.sendQuery(ctx, query, body)
+	conn.breaker.report(conn.getFQDN(), probe, err)
+
+	if err != nil {
+		// failure of the query after its context is done (e.g. by CancelAll) is matched with error of the context
+		if cause := ctx.Err(); cause != nil && !errors.Is(err, cause) {
+			err = &doneError{err: err, cause: cause}
+		}
+
+		conn.inflight.done(key)
+
+		return nil, nil, err
+	}
+
+	conn.inflight.setQueryID(key, header.Get("X-ClickHouse-Query-Id"))
+
+	return inflightReader{ReadCloser: reader, done: func() { conn.inflight.done(key) }}, header, nil
 }
 
-func (conn *Conn) doQuery(query string) (io.ReadCloser, error) {
+// sendQuery sends query with all attempts
+func (conn *Conn) sendQuery(ctx context.Context, query string, body io.Reader) (io.ReadCloser, http.Header, error) {
 	var (
 		attempts uint32 = 0
 		req      *http.Request
 		res      *http.Response
 		err      error
+		// statusErr is error returned by server unlike err of transport
+		statusErr error
+		wait      time.Duration
+		cancel    context.CancelFunc = func() {}
 	)
 
-	for attempts < atomic.LoadUint32(&conn.attemptsAmount) {
+	if conn.isClosed() {
+		return nil, nil, ErrClosed
+	}
+
+	if maxBytes := atomic.LoadUint32(&conn.maxQueryBytes); maxBytes > 0 && uint32(len(query)) > maxBytes {
+		err := fmt.Errorf("query has %d bytes which exceeds limit of %d bytes "+
+			"(pass large values list with input() table function or temporary table instead)", len(query), maxBytes)
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return nil, nil, err
+	}
+
+	conn.validateSettings()
+
+	attemptsAmount, attemptWait := conn.getAttempts(ctx)
+
+	start := time.Now()
+	onProgress := getProgress(ctx)
+
+	// streamed body can be sent again only if it's possible to rewind it
+	seeker, isSeeker := body.(io.Seeker)
+	if body != nil && !isSeeker {
+		attemptsAmount = 1
+	}
+
+	for attempts < attemptsAmount {
+		// the first attempt is sent immediately and the retry number N waits Retry-After of the server or N * wait
+		// before the timeout of the retry is started
+		if attempts > 0 {
+			if wait == 0 {
+				wait = time.Duration(attempts*attemptWait) * time.Second
+			}
+
+			// there is no reason to wait if the next attempt can't be started before the deadline
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+				cancel()
+
+				last := "unknown"
+				if err != nil {
+					last = err.Error()
+				} else if statusErr != nil {
+					last = statusErr.Error()
+				}
+
+				err = fmt.Errorf("can't retry query after %s because deadline of query is in %s (last error: %s): %w",
+					wait, time.Until(deadline).Round(time.Millisecond), last, context.DeadlineExceeded)
+
+				message := fmt.Sprintf("Can't do request to host %s: %s", conn.getFQDN(), err.Error())
+				cfg.logger.error(message)
+
+				return nil, nil, fmt.Errorf("Can't do request to host %s: %w", conn.getFQDN(), err)
+			}
+
+			err = sleep(ctx, wait)
+			if err != nil {
+				cancel()
+
+				message := fmt.Sprintf("Can't do request to host %s: %s", conn.getFQDN(), err.Error())
+				cfg.logger.error(message)
+
+				return nil, nil, fmt.Errorf("Can't do request to host %s: %w", conn.getFQDN(), err)
+			}
+
+			wait = 0
+		}
+
 		maxMemoryUsage := atomic.LoadInt32(&conn.maxMemoryUsage)
 		connectTimeout := atomic.LoadInt32(&conn.connectTimeout)
 		sendTimeout := atomic.LoadInt32(&conn.sendTimeout)
 		receiveTimeout := atomic.LoadInt32(&conn.receiveTimeout)
 		compression := atomic.LoadInt32(&conn.compression)
+		if flag, ok := getCompression(ctx); ok {
+			compression = 0
+			if flag {
+				compression = 1
+			}
+		}
+		bodyCompress := atomic.LoadInt32(&conn.bodyCompress)
+		contentEncoding := getContentEncoding(ctx)
+		rawResponse := getRawResponse(ctx)
+		if len(contentEncoding) > 0 && body != nil {
+			// pre-compressed data is sent as is
+			bodyCompress = 0
+		}
+		waitEndOfQuery := atomic.LoadInt32(&conn.waitEndOfQuery)
+		bestEffort := atomic.LoadInt32(&conn.bestEffort)
 
 		var timeout int32 = 0
 
@@ -467,11 +2787,36 @@ func (conn *Conn) doQuery(query string) (io.ReadCloser, error) {
 			timeout += receiveTimeout
 		}
 
-		client := http.Client{}
+		// previous attempt is finished so its context isn't needed anymore
+		cancel()
+
+		// connection establishing is limited by connect timeout on dialing
+		// and the whole query including reading of response is limited by context
+		dialing := dialOptions{
+			retries: atomic.LoadUint32(&conn.connectRetries),
+			wait:    time.Duration(atomic.LoadUint32(&conn.connectWait)) * time.Millisecond}
+		if connectTimeout > 0 {
+			dialing.timeout = time.Duration(connectTimeout) * time.Second
+		}
+
+		reqCtx := context.WithValue(ctx, dialOptionsKey{}, dialing)
+
+		// the tightest of deadline of context and sum of timeouts limits the attempt
+		if deadline, ok := ctx.Deadline(); ok && (timeout <= 0 || time.Until(deadline) < time.Duration(timeout)*time.Second) {
+			message := fmt.Sprintf("The attempt is limited by deadline of context in %s", time.Until(deadline).Round(time.Millisecond))
+			cfg.logger.debug(message)
+		}
+
 		if timeout > 0 {
-			client.Timeout = time.Duration(timeout) * time.Second
+			reqCtx, cancel = context.WithTimeout(reqCtx, time.Duration(timeout)*time.Second)
+		} else {
+			reqCtx, cancel = context.WithCancel(reqCtx)
 		}
 
+		client := http.Client{
+			Transport:     conn.transport.get(),
+			CheckRedirect: conn.checkRedirect}
+
 		options := url.Values{}
 		if maxMemoryUsage > 0 {
 			options.Set("max_memory_usage", fmt.Sprintf("%d", maxMemoryUsage))
@@ -493,97 +2838,575 @@ func (conn *Conn) doQuery(query string) (io.ReadCloser, error) {
 			options.Set("enable_http_compression", fmt.Sprintf("%d", compression))
 		}
 
-		urlStr := conn.protocol + "://" + conn.getFQDN(true) + "/?" + options.Encode()
+		if waitEndOfQuery == 1 && insertRe.MatchString(query) {
+			options.Set("wait_end_of_query", "1")
+		}
+
+		if bestEffort == 1 && insertRe.MatchString(query) {
+			options.Set("date_time_input_format", "best_effort")
+		}
+
+		conn.mux.Lock()
+		protocol := conn.protocol
+		database := conn.database
+		token := conn.token
+		headers := copyMap(conn.headers)
+		for name, value := range conn.settings {
+			options.Set(name, value)
+		}
+		conn.mux.Unlock()
+
+		for name, value := range getSettings(ctx) {
+			options.Set(name, value)
+		}
+
+		if onProgress != nil {
+			options.Set("send_progress_in_http_headers", "1")
+		}
+
+		if len(database) > 0 {
+			options.Set("database", database)
+		}
+
+		method := "POST"
+
+		var reqBody io.Reader
+		if body == nil && conn.fitsGet(query, options) {
+			method = "GET"
+			options.Set("query", query)
+		} else if body == nil {
+			reqBody = strings.NewReader(query)
+		} else {
+			options.Set("query", query)
+
+			if attempts > 0 {
+				_, err = seeker.Seek(0, io.SeekStart)
+				if err != nil {
+					cancel()
+
+					return nil, nil, fmt.Errorf("can't rewind body to retry query: %w", err)
+				}
+			}
+
+			// prevents closing of caller's reader by HTTP client
+			reqBody = ioutil.NopCloser(body)
+		}
+
+		if bodyCompress == 1 && reqBody != nil {
+			reqBody = compressBody(reqBody, int(atomic.LoadInt32(&conn.bodyLevel)))
+		}
+
+		// credentials are escaped by URL so passwords with reserved symbols (e.g. @, / or #) are sent as is
+		reqURL := url.URL{
+			Scheme:   protocol,
+			Host:     fmt.Sprintf("%s:%d", conn.host, conn.port),
+			Path:     "/",
+			RawQuery: options.Encode()}
+		if len(token) == 0 {
+			reqURL.User = url.UserPassword(conn.user, conn.pass)
+		}
+
+		urlStr := reqURL.String()
 
-		req, err = http.NewRequest("POST", urlStr, strings.NewReader(query))
+		message := fmt.Sprintf("Send %s request to %s", method, maskedURL(protocol, conn.getFQDN(), options))
+		cfg.logger.debug(message)
+
+		req, err = http.NewRequestWithContext(reqCtx, method, urlStr, reqBody)
 		if err != nil {
-			message := fmt.Sprintf("Can't connect to host %s: %s", conn.getFQDN(false), err.Error())
+			cancel()
+
+			message := fmt.Sprintf("Can't connect to host %s: %s", conn.getFQDN(), err.Error())
 			cfg.logger.fatal(message)
 
-			return nil, errors.New(message)
+			return nil, nil, errors.New(message)
 		}
 
 		if compression == 1 {
 			req.Header.Add("Accept-Encoding", "gzip")
 		}
 		req.Header.Set("Content-Type", "text/plain")
-		req.Header.Set("Pragma", "no-cache")
-		req.Header.Set("Cache-Control", "no-cache")
+		if bodyCompress == 1 && reqBody != nil {
+			req.Header.Set("Content-Encoding", "gzip")
+		} else if len(contentEncoding) > 0 && body != nil {
+			req.Header.Set("Content-Encoding", contentEncoding)
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
 
-		req.Close = true
+		// content type of the query takes precedence over headers of connection
+		if contentType, ok := getContentType(ctx); ok {
+			req.Header.Set("Content-Type", contentType)
+		}
 
-		if attempts > 0 {
-			exponentialTime := attempts * conn.attemptWait
+		if len(token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		// redirected request has to send the body again
+		if reqBody != nil && (body == nil || isSeeker) {
+			req.GetBody = func() (io.ReadCloser, error) {
+				var redirectBody io.Reader = strings.NewReader(query)
+				if body != nil {
+					_, err := seeker.Seek(0, io.SeekStart)
+					if err != nil {
+						return nil, fmt.Errorf("can't rewind body to follow redirect: %w", err)
+					}
+
+					redirectBody = body
+				}
+
+				if bodyCompress == 1 {
+					return compressBody(redirectBody, int(atomic.LoadInt32(&conn.bodyLevel))), nil
+				}
 
-			time.Sleep(time.Duration(exponentialTime) * time.Second)
+				return ioutil.NopCloser(redirectBody), nil
+			}
 		}
 
+		req.Close = atomic.LoadInt32(&conn.keepAlive) != 1
+
 		attempts++
 
+		statusErr = nil
 		res, err = client.Do(req)
+		if err != nil {
+			err = timeoutError(ctx, err, timeout)
+		}
+
+		if err == nil && rawResponse != nil {
+			*rawResponse = res
+
+			return cancelReader{ReadCloser: res.Body, cancel: cancel}, res.Header, nil
+		}
+
+		if err == nil && compression == 1 && !insertRe.MatchString(query) {
+			conn.checkCompression(res)
+		}
 
-		if atomic.LoadUint32(&conn.attemptsAmount) > 1 {
+		if attemptsAmount > 1 {
 			if err != nil {
 				message := fmt.Sprintf("Catch warning %s", err.Error())
 				cfg.logger.warn(message)
 
 				if strings.Contains(err.Error(), "Memory limit") {
-					return nil, errors.New(message)
+					cancel()
+
+					return nil, nil, errors.New(message)
 				}
-			} else if err = handleErrStatus(res); err != nil {
-				message := fmt.Sprintf("Catch warning %s", err.Error())
+			} else if statusErr = handleErrStatus(res); statusErr != nil {
+				message := fmt.Sprintf("Catch warning %s", statusErr.Error())
 				cfg.logger.warn(message)
+
+				if !isRetryable(statusErr) {
+					break
+				}
+
+				wait = getRetryAfter(res)
+
+				// overloaded server has to get some time even if attempts wait isn't set
+				if wait == 0 && errors.Is(statusErr, ErrTooManyQueries) && attemptWait == 0 {
+					wait = time.Duration(attempts) * tooManyQueriesWait
+				}
 			} else {
-				return getReader(res)
+				reader, err := getReader(res, conn.getBufferSize())
+				if err != nil {
+					cancel()
+
+					return nil, nil, err
+				}
+
+				if onProgress != nil {
+					reportProgress(res.Header, start, onProgress)
+				}
+
+				return cancelReader{ReadCloser: reader, cancel: cancel}, res.Header, nil
 			}
 		}
 	}
 
+	// status of the only attempt isn't checked in the loop
+	if err == nil && statusErr == nil {
+		statusErr = handleErrStatus(res)
+	}
+
 	if err != nil {
-		message := fmt.Sprintf("Can't do request to host %s: %s", conn.getFQDN(false), err.Error())
+		cancel()
+
+		err = &ConnectionError{Host: conn.getFQDN(), Err: err}
+
+		message := err.Error()
 		cfg.logger.error(message)
 
-		return nil, errors.New(message)
-	} else if err = handleErrStatus(res); err != nil {
+		return nil, nil, err
+	} else if statusErr != nil {
+		cancel()
+
+		message := fmt.Sprintf("Catch error %s", statusErr.Error())
+		cfg.logger.error(message)
+
+		return nil, nil, fmt.Errorf("Catch error %w", statusErr)
+	}
+
+	reader, err := getReader(res, conn.getBufferSize())
+	if err != nil {
+		cancel()
+
 		message := fmt.Sprintf("Catch error %s", err.Error())
 		cfg.logger.error(message)
 
-		return nil, errors.New(message)
+		return nil, nil, err
+	}
+
+	if onProgress != nil {
+		reportProgress(res.Header, start, onProgress)
+	}
+
+	return cancelReader{ReadCloser: reader, cancel: cancel}, res.Header, nil
+}
+
+// timeoutError describes which bound of the query is exceeded: deadline of context or sum of timeouts of connection
+// or if the query is cancelled
+func timeoutError(ctx context.Context, err error, timeout int32) error {
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("query is cancelled: %w", err)
+	} else if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("deadline of context is exceeded: %w", err)
+	} else if timeout > 0 {
+		return fmt.Errorf("timeout of %d s (sum of connect, send and receive timeouts) is exceeded: %w", timeout, err)
 	}
 
-	return res.Body, nil
+	return err
+}
+
+// maskedURL returns URL of request for logging with masked password and without query which is logged separately
+func maskedURL(protocol, address string, options url.Values) string {
+	logged := url.Values{}
+	for name, values := range options {
+		if name != "query" {
+			logged[name] = values
+		}
+	}
+
+	return protocol + "://" + address + "/?" + logged.Encode()
+}
+
+// sleep waits for passed duration unless context is done
+func sleep(ctx context.Context, wait time.Duration) error {
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var insertRe = regexp.MustCompile(`(?i)^\s*INSERT\b`)
+
+var readOnlyRe = regexp.MustCompile(`(?i)^\s*(SELECT|WITH|SHOW|DESC|DESCRIBE|EXISTS|EXPLAIN)\b`)
+
+func (conn *Conn) fitsGet(query string, options url.Values) bool {
+	maxLength := atomic.LoadUint32(&conn.getMaxLength)
+	if maxLength == 0 || !readOnlyRe.MatchString(query) {
+		return false
+	}
+
+	// the estimation doesn't include scheme and host
+	length := len(options.Encode()) + len(url.QueryEscape(query)) + len("/?&query=")
+
+	return uint32(length) <= maxLength
+}
+
+// compressBody returns reader of gzip compressed body
+// The compression goroutine stops when the reader is closed by HTTP client
+func compressBody(body io.Reader, level int) io.ReadCloser {
+	reader, writer := io.Pipe()
+
+	go func() {
+		gz, err := gzip.NewWriterLevel(writer, level)
+		if err == nil {
+			_, err = io.Copy(gz, body)
+		}
+
+		if err == nil {
+			err = gz.Close()
+		}
+
+		writer.CloseWithError(err)
+	}()
+
+	return reader
+}
+
+// checkRedirect limits amount of redirects and passes credentials to the redirect target
+// which are dropped by HTTP client otherwise
+func (conn *Conn) checkRedirect(req *http.Request, via []*http.Request) error {
+	maxRedirects := atomic.LoadUint32(&conn.maxRedirects)
+	if uint32(len(via)) > maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+
+	original := via[0]
+
+	if original.URL.User != nil && req.URL.User == nil {
+		req.URL.User = original.URL.User
+	}
+
+	if auth := original.Header.Get("Authorization"); len(auth) > 0 && len(req.Header.Get("Authorization")) == 0 {
+		req.Header.Set("Authorization", auth)
+	}
+
+	message := fmt.Sprintf("Follow redirect to %s://%s", req.URL.Scheme, req.URL.Host)
+	cfg.logger.debug(message)
+
+	return nil
+}
+
+type dialOptionsKey struct{}
+
+// dialOptions are passed to dialing of shared transport by context of request
+type dialOptions struct {
+	timeout time.Duration
+	retries uint32
+	wait    time.Duration
+}
+
+// dialContext establishes connection limited by connect timeout and retries it according to options passed with context
+func dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	options, _ := ctx.Value(dialOptionsKey{}).(dialOptions)
+
+	dialer := net.Dialer{
+		Timeout:   options.timeout,
+		KeepAlive: 30 * time.Second}
+
+	var retries uint32 = 0
+	for {
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err == nil || retries >= options.retries || ctx.Err() != nil {
+			return conn, err
+		}
+
+		retries++
+
+		message := fmt.Sprintf("Catch warning can't connect to %s (retry %d of %d): %s", address, retries, options.retries, err.Error())
+		cfg.logger.warn(message)
+
+		select {
+		case <-time.After(time.Duration(retries) * options.wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// cancelReader releases context of query when response is closed
+type cancelReader struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (reader cancelReader) Close() error {
+	err := reader.ReadCloser.Close()
+	reader.cancel()
+
+	return err
+}
+
+// rewindReader is buffered reader which can be rewound with its source
+type rewindReader struct {
+	*bufio.Reader
+	source io.ReadSeeker
+}
+
+func (reader rewindReader) Seek(offset int64, whence int) (int64, error) {
+	position, err := reader.source.Seek(offset, whence)
+	if err == nil {
+		reader.Reader.Reset(reader.source)
+	}
+
+	return position, err
+}
+
+// progressReader reports amount of read bytes to callback
+type progressReader struct {
+	reader   io.Reader
+	read     int64
+	callback func(bytesSent int64)
+}
+
+// seekingProgressReader is progress reader which keeps possibility to rewind its source
+type seekingProgressReader struct {
+	*progressReader
+	seeker io.Seeker
+}
+
+func newProgressReader(reader io.Reader, callback func(bytesSent int64)) io.Reader {
+	progress := &progressReader{
+		reader:   reader,
+		callback: callback}
+
+	if seeker, ok := reader.(io.Seeker); ok {
+		return seekingProgressReader{
+			progressReader: progress,
+			seeker:         seeker}
+	}
+
+	return progress
+}
+
+func (reader *progressReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	if n > 0 {
+		reader.read += int64(n)
+		reader.callback(reader.read)
+	}
+
+	return n, err
+}
+
+func (reader seekingProgressReader) Seek(offset int64, whence int) (int64, error) {
+	position, err := reader.seeker.Seek(offset, whence)
+	if err == nil {
+		reader.read = position
+	}
+
+	return position, err
+}
+
+// checkCompression logs if server responds without compression which is requested (e.g. it's disabled by server settings)
+// The response is read as is so queries don't fail but compression does nothing
+func (conn *Conn) checkCompression(res *http.Response) {
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices || res.ContentLength == 0 ||
+		len(res.Header.Get("Content-Encoding")) > 0 {
+		return
+	}
+
+	message := fmt.Sprintf("Catch warning compression is requested but host %s responds without compression", conn.getFQDN())
+
+	// the first time is warning while the rest responses are logged as debug to not flood logs
+	if atomic.CompareAndSwapInt32(&conn.uncompressed, 0, 1) {
+		cfg.logger.warn(message)
+	} else {
+		cfg.logger.debug(message)
+	}
 }
 
-func getReader(res *http.Response) (io.ReadCloser, error) {
+func getReader(res *http.Response, size int) (io.ReadCloser, error) {
 	switch res.Header.Get("Content-Encoding") {
 	case "gzip":
-		reader, err := gzip.NewReader(res.Body)
-		if err != nil {
-			return nil, err
+		// gzip reads compressed data byte by byte so it's buffered with the fetch buffer size too
+		reader, err := gzip.NewReader(bufio.NewReaderSize(res.Body, size))
+		if err == io.EOF {
+			// there is no data at all
+			return res.Body, nil
+		} else if err != nil {
+			res.Body.Close()
+
+			return nil, fmt.Errorf("can't read gzip response: %w", err)
 		}
 
-		return reader, nil
-	default:
+		// data after the end of gzip stream isn't a part of response
+		reader.Multistream(false)
+
+		return gzipReader{buffer: bufio.NewReaderSize(reader, size), gzip: reader, body: res.Body}, nil
+	case "", "identity":
 		return res.Body, nil
+	default:
+		// gzip is the only requested encoding so the rest can't be decoded
+		res.Body.Close()
+
+		return nil, fmt.Errorf("can't read response with unsupported encoding %s", res.Header.Get("Content-Encoding"))
+	}
+}
+
+// gzipReader reads decompressed response in chunks of the fetch buffer size,
+// closes response body with gzip reader and describes errors of broken stream
+type gzipReader struct {
+	buffer *bufio.Reader
+	gzip   *gzip.Reader
+	body   io.ReadCloser
+}
+
+func (reader gzipReader) Read(p []byte) (int, error) {
+	n, err := reader.buffer.Read(p)
+	if err == io.ErrUnexpectedEOF {
+		err = fmt.Errorf("gzip response is truncated (the connection is closed or the query is killed): %w", err)
+	} else if err != nil && err != io.EOF {
+		err = fmt.Errorf("can't read gzip response: %w", err)
+	}
+
+	return n, err
+}
+
+func (reader gzipReader) Close() error {
+	err := reader.gzip.Close()
+	if bodyErr := reader.body.Close(); err == nil {
+		err = bodyErr
+	}
+
+	return err
+}
+
+// getRetryAfter returns delay requested by server with Retry-After header of 429 or 503 response
+func getRetryAfter(res *http.Response) time.Duration {
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+
+	value := res.Header.Get("Retry-After")
+	if len(value) == 0 {
+		return 0
+	}
+
+	var wait time.Duration
+
+	seconds, err := strconv.Atoi(value)
+	if err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = time.Until(date)
+	}
+
+	if wait <= 0 {
+		return 0
 	}
+
+	message := fmt.Sprintf("Server asks to retry after %s", wait)
+	cfg.logger.debug(message)
+
+	return wait
 }
 
+// handleErrStatus returns error of response with status out of 2xx range
+// Successful responses may have other statuses than 200 (e.g. 204 without content from proxies)
 func handleErrStatus(res *http.Response) error {
-	if res.StatusCode != 200 {
-		reader, err := getReader(res)
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		// status of the query isn't lost if the body can't be read (e.g. broken compression)
+		reader, err := getReader(res, defaultBufferSize)
 		if err != nil {
-			return err
+			return newQueryError(res, err.Error())
 		}
 		defer reader.Close()
 
 		bytes, err := ioutil.ReadAll(reader)
 
 		if err != nil {
-			return err
+			return newQueryError(res, err.Error())
 		}
 
 		if len(bytes) == 0 {
-			return errors.New("empty error body")
+			return newQueryError(res, "empty error body")
 		}
 
 		text := string(bytes)
@@ -592,10 +3415,12 @@ func handleErrStatus(res *http.Response) error {
 			re := regexp.MustCompile("<title>([^<]+)</title>")
 			list := re.FindAllString(text, -1)
 
-			return errors.New(list[0])
-		} else {
-			return errors.New(text)
+			if len(list) > 0 {
+				text = list[0]
+			}
 		}
+
+		return newQueryError(res, text)
 	}
 
 	return nil
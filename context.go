@@ -0,0 +1,153 @@
+package clickhouse
+
+import (
+	"context"
+	"strconv"
+)
+
+type settingsKey struct{}
+
+// WithSettings returns copy of context with settings which are sent only with queries executed with the context
+// The settings are merged with settings of parent context and override connection settings
+func WithSettings(ctx context.Context, settings map[string]string) context.Context {
+	merged := copyMap(getSettings(ctx))
+	for name, value := range settings {
+		merged[name] = value
+	}
+
+	return context.WithValue(ctx, settingsKey{}, merged)
+}
+
+// WithSetting returns copy of context with one setting
+func WithSetting(ctx context.Context, name, value string) context.Context {
+	return WithSettings(ctx, map[string]string{name: value})
+}
+
+// WithQueryID returns copy of context with query_id which is sent with queries executed with the context
+func WithQueryID(ctx context.Context, id string) context.Context {
+	return WithSetting(ctx, "query_id", id)
+}
+
+// WithReplacingQueryID returns copy of context with query_id and replace_running_query setting
+// so running query with the same id is cancelled by server and replaced with the new one
+func WithReplacingQueryID(ctx context.Context, id string) context.Context {
+	return WithSettings(ctx, map[string]string{
+		"query_id":              id,
+		"replace_running_query": "1"})
+}
+
+// WithLogComment returns copy of context with log_comment setting which is written into system.query_log
+// It overrides comment set by LogComment of connection
+func WithLogComment(ctx context.Context, comment string) context.Context {
+	return WithSetting(ctx, "log_comment", comment)
+}
+
+// LoadBalancing is algorithm of replica selection for distributed queries
+type LoadBalancing string
+
+const (
+	LoadBalancingRandom        LoadBalancing = "random"
+	LoadBalancingNearestHost   LoadBalancing = "nearest_hostname"
+	LoadBalancingInOrder       LoadBalancing = "in_order"
+	LoadBalancingFirstOrRandom LoadBalancing = "first_or_random"
+	LoadBalancingRoundRobin    LoadBalancing = "round_robin"
+)
+
+// WithLoadBalancing returns copy of context with load_balancing setting
+// which defines how replicas are selected by distributed queries executed with the context
+func WithLoadBalancing(ctx context.Context, balancing LoadBalancing) context.Context {
+	return WithSetting(ctx, "load_balancing", string(balancing))
+}
+
+// WithPreferLocalhostReplica returns copy of context with prefer_localhost_replica setting
+// so distributed queries executed with the context do (or don't) prefer local replica
+func WithPreferLocalhostReplica(ctx context.Context, flag bool) context.Context {
+	value := "0"
+	if flag {
+		value = "1"
+	}
+
+	return WithSetting(ctx, "prefer_localhost_replica", value)
+}
+
+func getSettings(ctx context.Context) map[string]string {
+	settings, _ := ctx.Value(settingsKey{}).(map[string]string)
+
+	return settings
+}
+
+// WithSequentialConsistency returns copy of context with select_sequential_consistency setting
+// so queries executed with the context read data of all inserts acknowledged by quorum (read-after-write consistency)
+// It slows down queries so it's better to use it only for reading of own writes
+func WithSequentialConsistency(ctx context.Context) context.Context {
+	return WithSetting(ctx, "select_sequential_consistency", "1")
+}
+
+// WithPrettyOutput returns copy of context with display settings of Pretty formats for queries executed with the context
+// maxRows limits amount of rendered rows (output_format_pretty_max_rows, zero keeps the server default),
+// color turns on or off ANSI colors (output_format_pretty_color)
+func WithPrettyOutput(ctx context.Context, maxRows int, color bool) context.Context {
+	settings := map[string]string{"output_format_pretty_color": "0"}
+	if color {
+		settings["output_format_pretty_color"] = "1"
+	}
+
+	if maxRows > 0 {
+		settings["output_format_pretty_max_rows"] = strconv.Itoa(maxRows)
+	}
+
+	return WithSettings(ctx, settings)
+}
+
+type compressionKey struct{}
+
+// WithResponseCompression returns copy of context which turns on or off compression of responses of queries executed with the context
+// It overrides Compression of connection (e.g. small interactive queries skip compression while bulk exports use it)
+func WithResponseCompression(ctx context.Context, flag bool) context.Context {
+	return context.WithValue(ctx, compressionKey{}, flag)
+}
+
+func getCompression(ctx context.Context) (flag bool, ok bool) {
+	flag, ok = ctx.Value(compressionKey{}).(bool)
+
+	return
+}
+
+type attemptsKey struct{}
+
+// withAttempts returns copy of context with attempts of query options which override attempts of connection
+func withAttempts(ctx context.Context, options QueryOptions) context.Context {
+	return context.WithValue(ctx, attemptsKey{}, options)
+}
+
+func getAttemptsOptions(ctx context.Context) (options QueryOptions, ok bool) {
+	options, ok = ctx.Value(attemptsKey{}).(QueryOptions)
+
+	return
+}
+
+type contentTypeKey struct{}
+
+// withContentType returns copy of context with content type of body of queries executed with the context
+func withContentType(ctx context.Context, contentType string) context.Context {
+	return context.WithValue(ctx, contentTypeKey{}, contentType)
+}
+
+func getContentType(ctx context.Context) (contentType string, ok bool) {
+	contentType, ok = ctx.Value(contentTypeKey{}).(string)
+
+	return
+}
+
+type contentEncodingKey struct{}
+
+// withContentEncoding returns copy of context with encoding of already compressed body of queries executed with the context
+func withContentEncoding(ctx context.Context, encoding string) context.Context {
+	return context.WithValue(ctx, contentEncodingKey{}, encoding)
+}
+
+func getContentEncoding(ctx context.Context) string {
+	encoding, _ := ctx.Value(contentEncodingKey{}).(string)
+
+	return encoding
+}
@@ -0,0 +1,124 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const defaultDDLTimeout = 180 * time.Second
+
+// DDLHostStatus describes result of distributed DDL query on one host
+type DDLHostStatus struct {
+	Host   string
+	Port   uint16
+	Code   int64
+	Error  string
+	Failed bool
+	// TimedOut is true if the host didn't finish the query in time
+	TimedOut bool
+}
+
+// DDLError is returned by ExecDDL if some hosts failed the query or didn't finish it in time
+type DDLError struct {
+	Hosts []DDLHostStatus
+}
+
+// Error returns list of failed hosts
+func (err *DDLError) Error() string {
+	list := make([]string, 0, len(err.Hosts))
+	for _, host := range err.Hosts {
+		if host.TimedOut {
+			list = append(list, fmt.Sprintf("%s:%d (timeout)", host.Host, host.Port))
+		} else {
+			list = append(list, fmt.Sprintf("%s:%d (code %d: %s)", host.Host, host.Port, host.Code, host.Error))
+		}
+	}
+
+	return "distributed DDL query failed on " + strings.Join(list, ", ")
+}
+
+var onClusterRe = regexp.MustCompile(`(?i)\bON\s+CLUSTER\b`)
+
+// ExecDDL executes DDL query and waits for the query is finished on all hosts of the cluster (for ON CLUSTER queries)
+// The waiting is limited with context deadline or 180 seconds
+// It returns *DDLError listing hosts which failed the query or didn't finish it in time
+func (conn *Conn) ExecDDL(ctx context.Context, query string) error {
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	if !onClusterRe.MatchString(query) {
+		message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+		cfg.logger.debug(message)
+
+		_, err := conn.exec(ctx, query, nil)
+
+		return err
+	}
+
+	timeout := defaultDDLTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	ctx = WithSettings(ctx, map[string]string{
+		"distributed_ddl_task_timeout": fmt.Sprintf("%d", int64(math.Ceil(timeout.Seconds()))),
+		"distributed_ddl_output_mode":  "null_status_on_timeout",
+		"default_format":               string(TSVWithNames)})
+
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	iter, err := conn.openIter(ctx, query, TSVWithNames, nil)
+	if err != nil {
+		return err
+	}
+
+	defer iter.Close()
+
+	var failed []DDLHostStatus
+	for iter.Next() {
+		status := DDLHostStatus{}
+		status.Host, _ = iter.Result.String("host")
+		status.Port, _ = iter.Result.UInt16("port")
+		status.Error, _ = iter.Result.String("error")
+
+		if iter.Result.data["status"] == `\N` {
+			status.TimedOut = true
+		} else {
+			status.Code, err = iter.Result.Int64("status")
+			if err != nil {
+				return err
+			}
+
+			status.Failed = status.Code != 0
+		}
+
+		if status.TimedOut || status.Failed {
+			failed = append(failed, status)
+		}
+	}
+
+	err = iter.Err()
+	if err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		err = &DDLError{Hosts: failed}
+
+		message = fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return err
+	}
+
+	message = fmt.Sprintf("The query is executed %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	return nil
+}
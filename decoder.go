@@ -0,0 +1,301 @@
+package clickhouse
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// RowDecoder reads rows of fetched data for iterator
+type RowDecoder interface {
+	// ReadRow returns values of the next row by columns
+	// It returns io.EOF if there are no more rows and error wrapping ErrBadRow if the row is malformed but the next rows can be read
+	ReadRow() (map[string]string, error)
+	// Columns returns names of columns known so far in order of the query
+	Columns() []string
+}
+
+// rowReuser is implemented by decoders which can fill map of the previous row instead of creating new one
+type rowReuser interface {
+	// ReadRowInto sets values of the next row into data which has values of the previous row
+	ReadRowInto(data map[string]string) error
+}
+
+// DecoderFactory creates row decoder over response of query
+type DecoderFactory func(reader *bufio.Reader) (RowDecoder, error)
+
+var (
+	decoders = map[Format]DecoderFactory{
+		TSVWithNames:         newTSVDecoder,
+		TSVWithNamesAndTypes: newTSVTypesDecoder,
+		CSVWithNames:         newCSVDecoder,
+		JSONEachRow:          newJSONDecoder}
+	decodersMux sync.RWMutex
+)
+
+// RegisterDecoder sets decoder of format so the format can be fetched with iterator
+// It replaces built-in decoder if the format already has one
+func RegisterDecoder(format Format, factory DecoderFactory) {
+	decodersMux.Lock()
+	decoders[format] = factory
+	decodersMux.Unlock()
+
+	message := fmt.Sprintf("Set decoder of format `%s`", format)
+	cfg.logger.debug(message)
+}
+
+func getDecoder(format Format) (DecoderFactory, bool) {
+	decodersMux.RLock()
+	defer decodersMux.RUnlock()
+
+	factory, ok := decoders[format]
+
+	return factory, ok
+}
+
+// readLine returns the next line without line break (the last line may have no line break)
+// The line is read from buffer of the reader so there is no allocation except of the returned string
+func readLine(reader *bufio.Reader) (string, error) {
+	bytes, err := reader.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// the line is longer than the buffer so its parts are accumulated
+		line := append([]byte(nil), bytes...)
+		for err == bufio.ErrBufferFull {
+			bytes, err = reader.ReadSlice('\n')
+			line = append(line, bytes...)
+		}
+
+		bytes = line
+	}
+
+	if err == io.EOF && len(bytes) > 0 {
+		err = nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if length := len(bytes); bytes[length-1] == '\n' {
+		bytes = bytes[:length-1]
+	}
+
+	return string(bytes), nil
+}
+
+type tsvDecoder struct {
+	reader  *bufio.Reader
+	columns []string
+	fields  []string
+}
+
+func newTSVDecoder(reader *bufio.Reader) (RowDecoder, error) {
+	line, err := readLine(reader)
+	if err == io.EOF {
+		return nil, errors.New("can't get columns names")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &tsvDecoder{
+		reader:  reader,
+		columns: strings.Split(line, "\t")}, nil
+}
+
+func (decoder *tsvDecoder) ReadRow() (map[string]string, error) {
+	data := make(map[string]string, len(decoder.columns))
+
+	err := decoder.ReadRowInto(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (decoder *tsvDecoder) ReadRowInto(data map[string]string) error {
+	line, err := readLine(decoder.reader)
+	if err != nil {
+		return err
+	}
+
+	// fields are substrings of the line so only the slice of them is reused
+	decoder.fields = decoder.fields[:0]
+	for {
+		index := strings.IndexByte(line, '\t')
+		if index < 0 {
+			decoder.fields = append(decoder.fields, line)
+
+			break
+		}
+
+		decoder.fields = append(decoder.fields, line[:index])
+		line = line[index+1:]
+	}
+
+	if len(decoder.fields) != len(decoder.columns) {
+		return fmt.Errorf("%w: row has %d fields instead of %d: %s", ErrBadRow, len(decoder.fields), len(decoder.columns),
+			cutOffQuery(strings.Join(decoder.fields, "\t"), 100))
+	}
+
+	for index, column := range decoder.columns {
+		data[column] = decoder.fields[index]
+	}
+
+	return nil
+}
+
+func (decoder *tsvDecoder) Columns() []string {
+	return decoder.columns
+}
+
+// tsvTypesDecoder is TabSeparated decoder which knows types of columns
+type tsvTypesDecoder struct {
+	*tsvDecoder
+	types map[string]string
+}
+
+func newTSVTypesDecoder(reader *bufio.Reader) (RowDecoder, error) {
+	decoder, err := newTSVDecoder(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	line, err := readLine(reader)
+	if err == io.EOF {
+		return nil, errors.New("can't get columns types")
+	} else if err != nil {
+		return nil, err
+	}
+
+	columns := decoder.Columns()
+
+	types := strings.Split(line, "\t")
+	if len(types) != len(columns) {
+		return nil, fmt.Errorf("there are %d columns types for %d columns", len(types), len(columns))
+	}
+
+	typed := &tsvTypesDecoder{
+		tsvDecoder: decoder.(*tsvDecoder),
+		types:      make(map[string]string, len(columns))}
+
+	for index, column := range columns {
+		typed.types[column] = Unescape(types[index])
+	}
+
+	return typed, nil
+}
+
+func (decoder *tsvTypesDecoder) ColumnTypes() map[string]string {
+	return decoder.types
+}
+
+type csvDecoder struct {
+	reader  *csv.Reader
+	columns []string
+}
+
+func newCSVDecoder(reader *bufio.Reader) (RowDecoder, error) {
+	return newDelimitedCSVDecoder(reader, ',')
+}
+
+// csvDecoderFactory creates factory of CSV decoders with fields separated by delimiter (see format_csv_delimiter setting)
+func csvDecoderFactory(delimiter rune) DecoderFactory {
+	return func(reader *bufio.Reader) (RowDecoder, error) {
+		return newDelimitedCSVDecoder(reader, delimiter)
+	}
+}
+
+func newDelimitedCSVDecoder(reader *bufio.Reader, delimiter rune) (RowDecoder, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.Comma = delimiter
+
+	// the first record with names defines amount of fields
+	columns, err := csvReader.Read()
+	if err == io.EOF {
+		return nil, errors.New("can't get columns names")
+	} else if err != nil {
+		return nil, fmt.Errorf("can't get columns names: %s", err.Error())
+	}
+
+	// fields are copied into map of row so the slice of them can be reused after reading of names
+	csvReader.ReuseRecord = true
+
+	return &csvDecoder{
+		reader:  csvReader,
+		columns: columns}, nil
+}
+
+func (decoder *csvDecoder) ReadRow() (map[string]string, error) {
+	data := make(map[string]string, len(decoder.columns))
+
+	err := decoder.ReadRowInto(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (decoder *csvDecoder) ReadRowInto(data map[string]string) error {
+	fields, err := decoder.reader.Read()
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return fmt.Errorf("%w: %s", ErrBadRow, err.Error())
+	} else if err != nil {
+		return err
+	}
+
+	for index, column := range decoder.columns {
+		data[column] = fields[index]
+	}
+
+	return nil
+}
+
+func (decoder *csvDecoder) Columns() []string {
+	return decoder.columns
+}
+
+type jsonDecoder struct {
+	reader  *bufio.Reader
+	columns []string
+	known   map[string]bool
+}
+
+func newJSONDecoder(reader *bufio.Reader) (RowDecoder, error) {
+	// columns are known after reading of rows
+	return &jsonDecoder{
+		reader: reader,
+		known:  map[string]bool{}}, nil
+}
+
+func (decoder *jsonDecoder) ReadRow() (map[string]string, error) {
+	line, err := readLine(decoder.reader)
+	if err != nil {
+		return nil, err
+	}
+
+	data, columns, err := parseJSONRow([]byte(line))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBadRow, err.Error())
+	}
+
+	for _, column := range columns {
+		if !decoder.known[column] {
+			decoder.known[column] = true
+			decoder.columns = append(decoder.columns, column)
+		}
+	}
+
+	return data, nil
+}
+
+func (decoder *jsonDecoder) Columns() []string {
+	return decoder.columns
+}
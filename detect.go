@@ -0,0 +1,172 @@
+package clickhouse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// detectSize is amount of leading bytes of data used to detect its format
+const detectSize = 64 * 1024
+
+// InsertAuto inserts data into all columns of `database.table` table in format detected by the leading bytes of data
+// JSON objects (or array of them) are inserted as JSONEachRow while lines without header are inserted
+// as TabSeparated or CSV by delimiter of the first line
+// It returns error if the format is ambiguous (e.g. single column) so the format has to be set explicitly with InsertBatchContext
+func (conn *Conn) InsertAuto(database, table string, r io.Reader) error {
+	var start int64
+	seeker, isSeeker := r.(io.ReadSeeker)
+	if isSeeker {
+		var err error
+		start, err = seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			isSeeker = false
+		}
+	}
+
+	reader := bufio.NewReaderSize(r, detectSize)
+
+	head, err := reader.Peek(detectSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return err
+	}
+
+	if len(bytes.TrimSpace(head)) == 0 {
+		cfg.logger.debug("There is no data to insert")
+
+		return nil
+	}
+
+	format, err := detectFormat(head, err == io.EOF)
+	if err != nil {
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return err
+	}
+
+	message := fmt.Sprintf("Detected format `%s` of data to insert into %s.%s", format, database, table)
+	cfg.logger.debug(message)
+
+	var body io.Reader = reader
+	if isSeeker {
+		// the source is passed as is to keep possibility to retry the insert
+		_, err = seeker.Seek(start, io.SeekStart)
+		if err == nil {
+			body = seeker
+		}
+	}
+
+	return conn.InsertBatchContext(context.Background(), database, table, nil, format, body, InsertOptions{})
+}
+
+// detectFormat guesses format of data by its leading bytes (complete is true if there is no more data)
+func detectFormat(head []byte, complete bool) (Format, error) {
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+
+	trimmed := bytes.TrimLeft(head, " \t\r\n")
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && isJSONRows(trimmed, complete) {
+		return JSONEachRow, nil
+	}
+
+	line := head
+	if index := bytes.IndexByte(head, '\n'); index >= 0 {
+		line = head[:index]
+	} else if !complete {
+		return "", fmt.Errorf("can't detect format of data with the first line longer than %d bytes", detectSize)
+	}
+
+	tabs, commas := countDelimiters(bytes.TrimRight(line, "\r"))
+
+	switch {
+	case tabs > 0 && commas == 0:
+		return TSV, nil
+	case commas > 0 && tabs == 0:
+		return CSV, nil
+	case tabs > 0 && commas > 0:
+		return "", errors.New("can't detect format of data with both tabs and commas in the first line (set the format explicitly)")
+	}
+
+	return "", errors.New("can't detect format of data without delimiters in the first line (set the format explicitly)")
+}
+
+// isJSONRows checks if data starts with JSON object (or array of them) which isn't followed by delimiter of fields
+// so rows with Map or Array literal in the first column aren't taken as JSON
+func isJSONRows(head []byte, complete bool) bool {
+	decoder := json.NewDecoder(bytes.NewReader(head))
+
+	isArray := head[0] == '['
+	if isArray {
+		if _, err := decoder.Token(); err != nil {
+			return false
+		}
+	}
+
+	var first json.RawMessage
+	if err := decoder.Decode(&first); err != nil {
+		// the first object can be longer than the leading bytes
+		return !complete && errors.Is(err, io.ErrUnexpectedEOF)
+	}
+
+	if len(first) == 0 || first[0] != '{' {
+		return false
+	}
+
+	rest := bytes.TrimLeft(head[decoder.InputOffset():], " \r")
+	if len(rest) == 0 {
+		return true
+	}
+
+	switch {
+	case rest[0] == '\n' || rest[0] == '{':
+		return !isArray
+	case rest[0] == ',':
+		return isArray
+	case rest[0] == ']' && isArray:
+		rest = bytes.TrimLeft(rest[1:], " \r")
+
+		return len(rest) == 0 || rest[0] == '\n'
+	}
+
+	return false
+}
+
+// countDelimiters counts tabs and commas of line
+// (commas inside of double quoted CSV fields or brackets of array, tuple and map literals aren't counted)
+func countDelimiters(line []byte) (int, int) {
+	var (
+		tabs, commas, depth int
+		quoted              bool
+	)
+
+	for _, char := range line {
+		switch char {
+		case '"':
+			// escaped quote ("") toggles the state twice
+			quoted = !quoted
+		case '\t':
+			tabs++
+		case '[', '(', '{':
+			if !quoted {
+				depth++
+			}
+		case ']', ')', '}':
+			if !quoted && depth > 0 {
+				depth--
+			}
+		case ',':
+			if !quoted && depth == 0 {
+				commas++
+			}
+		}
+	}
+
+	return tabs, commas
+}
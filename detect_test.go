@@ -0,0 +1,67 @@
+package clickhouse
+
+import "testing"
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		head     string
+		complete bool
+		want     Format
+	}{
+		{"json object", `{"a":1,"b":"x"}` + "\n" + `{"a":2,"b":"y"}` + "\n", true, JSONEachRow},
+		{"json objects on one line", `{"a":1}{"a":2}`, true, JSONEachRow},
+		{"json array", `[{"a":1},{"a":2}]`, true, JSONEachRow},
+		{"json array with spaces", " [ {\"a\":1} , {\"a\":2} ]\n", true, JSONEachRow},
+		{"json with bom", "\xef\xbb\xbf{\"a\":1}\n", true, JSONEachRow},
+		{"long json object", `{"a":"very long`, false, JSONEachRow},
+		{"tsv", "1\tfoo\n2\tbar\n", true, TSV},
+		{"tsv with crlf", "1\tfoo\r\n", true, TSV},
+		{"tsv with array", "[1,2]\tfoo\n", true, TSV},
+		{"tsv with map", "{'a':1}\tfoo\n", true, TSV},
+		{"tsv with json string", "{\"a\":1}\tfoo\n", true, TSV},
+		{"tsv with array of json", "[{\"a\":1}]\tfoo\n", true, TSV},
+		{"csv", "1,foo\n2,bar\n", true, CSV},
+		{"csv with array", "\"[1,2]\",foo\n", true, CSV},
+		{"csv with unquoted array", "[1,2],foo\n", true, CSV},
+		{"csv with json string", "{\"a\":1},foo\n", true, CSV},
+		{"without newline", "1\tfoo", true, TSV},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := detectFormat([]byte(test.head), test.complete)
+			if err != nil {
+				t.Fatalf("detectFormat(%q) returned error: %s", test.head, err.Error())
+			}
+
+			if got != test.want {
+				t.Errorf("detectFormat(%q) = %s, want %s", test.head, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDetectFormatErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		head     string
+		complete bool
+	}{
+		{"empty", "", true},
+		{"single column", "1\n2\n", true},
+		{"single array column", "[1,2]\n", true},
+		{"tabs and commas", "1\ta,b\n", true},
+		{"long first line", "1\tfoo", false},
+		{"broken json", "{\"a\":}\n", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := detectFormat([]byte(test.head), test.complete)
+			if err == nil {
+				t.Errorf("detectFormat(%q) = %s, want error", test.head, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package clickhouse
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeFormat defines how time.Time values are written by RowWriter
+type TimeFormat int
+
+const (
+	// TimeDateTime writes time as DateTime (2006-01-02 15:04:05)
+	TimeDateTime TimeFormat = iota
+	// TimeDate writes time as Date (2006-01-02)
+	TimeDate
+	// TimeDateTime64 writes time as DateTime64 with fractional seconds (2006-01-02 15:04:05.999999999)
+	TimeDateTime64
+	// TimeUnix writes time as Unix timestamp in seconds
+	TimeUnix
+	// TimeDate32 writes time as Date32 (2006-01-02) and fails on dates out of its range from 1900-01-01 to 2299-12-31
+	TimeDate32
+)
+
+// RowWriter encodes Go values into TabSeparated rows to insert with InsertBatch
+type RowWriter struct {
+	w           io.Writer
+	timeFormat  TimeFormat
+	timeFormats map[int]TimeFormat
+	emptyAsNull bool
+}
+
+// NewRowWriter creates row writer over passed writer
+func NewRowWriter(w io.Writer) *RowWriter {
+	return &RowWriter{
+		w:           w,
+		timeFormats: map[int]TimeFormat{}}
+}
+
+// TimeFormat sets format of time.Time values for all columns (DateTime by default)
+func (rw *RowWriter) TimeFormat(format TimeFormat) {
+	rw.timeFormat = format
+}
+
+// ColumnTimeFormat sets format of time.Time values for column with passed index (starting with 0)
+// It overrides format set by TimeFormat
+func (rw *RowWriter) ColumnTimeFormat(index int, format TimeFormat) {
+	rw.timeFormats[index] = format
+}
+
+// EmptyAsNull turns on writing of empty strings as NULL (off by default)
+// It's intended for Nullable columns because empty string is valid value of String columns
+func (rw *RowWriter) EmptyAsNull(flag bool) {
+	rw.emptyAsNull = flag
+}
+
+// WriteRow encodes values and writes them as one TabSeparated row
+// Slices and arrays are written as array literals and maps are written as map literals
+// Nil pointers are written as NULL and other pointers are written as values they point to
+func (rw *RowWriter) WriteRow(values ...interface{}) error {
+	fields := make([]string, 0, len(values))
+	for index, value := range values {
+		value = indirect(value)
+
+		format, ok := rw.timeFormats[index]
+		if !ok {
+			format = rw.timeFormat
+		}
+
+		if rw.emptyAsNull && isEmptyString(value) {
+			fields = append(fields, `\N`)
+
+			continue
+		}
+
+		field, err := encodeField(value, format)
+		if err != nil {
+			return fmt.Errorf("can't encode field %d: %s", index, err.Error())
+		}
+
+		fields = append(fields, field)
+	}
+
+	_, err := io.WriteString(rw.w, strings.Join(fields, "\t")+"\n")
+
+	return err
+}
+
+// WriteStruct writes exported fields of struct (or pointer to struct) in order of declaration as one row
+// Fields tagged with `ch:"-"` are skipped and pointer fields are written as Nullable values
+func (rw *RowWriter) WriteStruct(value interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("can't write %T as struct", value)
+	}
+
+	rt := rv.Type()
+
+	values := make([]interface{}, 0, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if len(field.PkgPath) > 0 || field.Tag.Get("ch") == "-" {
+			continue
+		}
+
+		values = append(values, rv.Field(i).Interface())
+	}
+
+	return rw.WriteRow(values...)
+}
+
+// indirect returns value pointer points to or nil for nil pointer
+func indirect(value interface{}) interface{} {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+
+		rv = rv.Elem()
+	}
+
+	if !rv.IsValid() {
+		return nil
+	}
+
+	return rv.Interface()
+}
+
+func isEmptyString(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return len(v) == 0
+	case []byte:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+func encodeField(value interface{}, timeFormat TimeFormat) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return `\N`, nil
+	case string:
+		return Escape(v), nil
+	case []byte:
+		return Escape(string(v)), nil
+	case time.Time:
+		return encodeTime(v, timeFormat)
+	default:
+		return quoteValue(value)
+	}
+}
+
+func encodeTime(t time.Time, format TimeFormat) (string, error) {
+	switch format {
+	case TimeDateTime:
+		return t.Format("2006-01-02 15:04:05"), nil
+	case TimeDate:
+		return t.Format("2006-01-02"), nil
+	case TimeDateTime64:
+		return t.Format("2006-01-02 15:04:05.999999999"), nil
+	case TimeUnix:
+		return strconv.FormatInt(t.Unix(), 10), nil
+	case TimeDate32:
+		if err := checkDate32(t); err != nil {
+			return "", err
+		}
+
+		return t.Format("2006-01-02"), nil
+	default:
+		return "", fmt.Errorf("unknown time format %d", format)
+	}
+}
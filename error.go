@@ -0,0 +1,204 @@
+package clickhouse
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrClosed is returned by queries of closed connection
+	ErrClosed = errors.New("connection is closed")
+	// ErrAuthentication matches (with errors.Is) query errors caused by wrong credentials
+	ErrAuthentication = errors.New("authentication failed")
+	// ErrResultTooLarge matches (with errors.Is) query errors caused by exceeded result limit (see MaxResultRows)
+	ErrResultTooLarge = errors.New("result is too large")
+	// ErrTooManyQueries matches (with errors.Is) query errors caused by exceeded limit of simultaneous queries of the server
+	// Such queries are retried with backoff (see Attempts)
+	ErrTooManyQueries = errors.New("too many simultaneous queries")
+	// ErrConnection matches (with errors.Is) errors of network or transport (see ConnectionError)
+	ErrConnection = errors.New("connection failed")
+	// ErrCircuitOpen is returned by queries which aren't sent because circuit breaker of connection is open (see CircuitBreaker)
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	// ErrNoRows is returned by FetchExactlyOne if query has no rows
+	ErrNoRows = errors.New("no rows in result")
+	// ErrMultipleRows is wrapped by error of FetchExactlyOne if query has more than one row
+	ErrMultipleRows = errors.New("multiple rows in result")
+	// ErrStreamInterrupted matches (with errors.Is) errors of iterator if the stream is broken before its end
+	// (e.g. the query is killed by server or the connection is reset) so fetched rows are incomplete
+	ErrStreamInterrupted = errors.New("stream is interrupted")
+	// ErrBadRow is wrapped by errors of row decoders for malformed rows which can be skipped
+	ErrBadRow = errors.New("bad row")
+	// ErrIndexNotUsed matches (with errors.Is) query errors caused by queries which can't use index of table
+	// while ForcePrimaryKey or ForceIndexByDate is on
+	ErrIndexNotUsed = errors.New("index isn't used")
+	// ErrServerTimeout matches (with errors.Is) query errors caused by queries aborted by server
+	// after exceeding of max_execution_time setting
+	// unlike deadline of context (context.DeadlineExceeded) and cancellation (context.Canceled) of client
+	ErrServerTimeout = errors.New("query is aborted by server timeout")
+)
+
+// Clickhouse exception codes
+const (
+	codeUnknownUser          = 192
+	codeWrongPassword        = 193
+	codeRequiredPassword     = 194
+	codeAuthenticationFailed = 516
+	codeTooManyRowsOrBytes   = 396
+	codeTooManyQueries       = 202
+	codeIndexNotUsed         = 277
+	codeTimeoutExceeded      = 159
+)
+
+// QueryError describes failed query response of Clickhouse server
+type QueryError struct {
+	// StatusCode is HTTP status code of the response
+	StatusCode int
+	// Code is Clickhouse exception code or zero if it's unknown
+	Code int
+	// Message is error text returned by the server without stack trace
+	Message string
+	// Row is number of row of inserted data (starting from 1) which failed parsing or zero if it's unknown
+	Row int
+
+	stackTrace string
+}
+
+// ConnectionError describes failure of network or transport (e.g. refused connection, TLS handshake or timeout)
+// unlike QueryError returned by the server for failed query
+type ConnectionError struct {
+	// Host is host and port the query is sent to
+	Host string
+	// Err is error of the transport (usually *url.Error)
+	Err error
+}
+
+// Error returns error text
+func (err *ConnectionError) Error() string {
+	return fmt.Sprintf("Can't do request to host %s: %s", err.Host, err.Err.Error())
+}
+
+// Unwrap returns error of the transport
+func (err *ConnectionError) Unwrap() error {
+	return err.Err
+}
+
+// Is reports if the error matches ErrConnection
+func (err *ConnectionError) Is(target error) bool {
+	return target == ErrConnection
+}
+
+// interruptedError keeps error of broken stream matching ErrStreamInterrupted
+type interruptedError struct {
+	err error
+}
+
+func (err *interruptedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrStreamInterrupted.Error(), err.err.Error())
+}
+
+func (err *interruptedError) Unwrap() error {
+	return err.err
+}
+
+func (err *interruptedError) Is(target error) bool {
+	return target == ErrStreamInterrupted
+}
+
+// doneError keeps error of query failed after its context is done matching the error of context
+// (context.Canceled or context.DeadlineExceeded) even if transport reports it in another way
+type doneError struct {
+	err   error
+	cause error
+}
+
+func (err *doneError) Error() string {
+	return err.err.Error()
+}
+
+func (err *doneError) Unwrap() error {
+	return err.err
+}
+
+func (err *doneError) Is(target error) bool {
+	return target == err.cause
+}
+
+// Error returns error text
+func (err *QueryError) Error() string {
+	return err.Message
+}
+
+// StackTrace returns stack trace and nested causes returned by the server after the message or empty string
+func (err *QueryError) StackTrace() string {
+	return err.stackTrace
+}
+
+// Is reports if the error matches one of the sentinel errors
+func (err *QueryError) Is(target error) bool {
+	switch target {
+	case ErrAuthentication:
+		switch err.Code {
+		case codeUnknownUser, codeWrongPassword, codeRequiredPassword, codeAuthenticationFailed:
+			return true
+		}
+
+		return err.StatusCode == http.StatusUnauthorized
+	case ErrResultTooLarge:
+		return err.Code == codeTooManyRowsOrBytes
+	case ErrTooManyQueries:
+		return err.Code == codeTooManyQueries
+	case ErrIndexNotUsed:
+		return err.Code == codeIndexNotUsed
+	case ErrServerTimeout:
+		return err.Code == codeTimeoutExceeded
+	}
+
+	return false
+}
+
+// isRetryable checks if failed query can succeed on the next attempt
+// There is no sense to retry with the same credentials, the same limit of result or time or the same unindexed query
+func isRetryable(err error) bool {
+	return !errors.Is(err, ErrAuthentication) && !errors.Is(err, ErrResultTooLarge) &&
+		!errors.Is(err, ErrIndexNotUsed) && !errors.Is(err, ErrServerTimeout)
+}
+
+var (
+	codeRe = regexp.MustCompile(`Code: (\d+)`)
+	rowRe  = regexp.MustCompile(`\(at row (\d+)\)`)
+	// stackRe matches beginning of stack trace (e.g. ", Stack trace (when copying this message, always include the lines below):")
+	stackRe = regexp.MustCompile(`,?\s*Stack trace[^:\n]*:`)
+)
+
+func newQueryError(res *http.Response, text string) *QueryError {
+	err := &QueryError{
+		StatusCode: res.StatusCode,
+		Message:    text}
+
+	if loc := stackRe.FindStringIndex(text); loc != nil {
+		err.Message = strings.TrimSpace(text[:loc[0]])
+		err.stackTrace = strings.TrimSpace(text[loc[1]:])
+	}
+
+	if matches := rowRe.FindStringSubmatch(text); len(matches) > 1 {
+		err.Row, _ = strconv.Atoi(matches[1])
+	}
+
+	code, convErr := strconv.Atoi(res.Header.Get("X-ClickHouse-Exception-Code"))
+	if convErr == nil {
+		err.Code = code
+
+		return err
+	}
+
+	matches := codeRe.FindStringSubmatch(text)
+	if len(matches) > 1 {
+		err.Code, _ = strconv.Atoi(matches[1])
+	}
+
+	return err
+}
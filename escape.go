@@ -1,77 +1,291 @@
 package clickhouse
 
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NullField is TabSeparated NULL which EscapeRow writes as is
+const NullField = `\N`
+
 // Escape escapes special symbols
 func Escape(line string) string {
-	result := ""
+	var result strings.Builder
+	result.Grow(len(line))
 
-	length := len(line)
-	for i := 0; i < length; i++ {
-		char := line[i : i+1]
+	writeEscaped(&result, line)
+
+	return result.String()
+}
+
+// EscapeRow returns TabSeparated row of fields escaped by Escape and joined with tabs (without line break)
+// Fields equal to NullField are written as NULL
+func EscapeRow(fields []string) string {
+	var (
+		result strings.Builder
+		size   = len(fields)
+	)
+
+	for _, field := range fields {
+		size += len(field)
+	}
+
+	result.Grow(size)
+
+	for index, field := range fields {
+		if index > 0 {
+			result.WriteByte('\t')
+		}
+
+		if field == NullField {
+			result.WriteString(field)
+		} else {
+			writeEscaped(&result, field)
+		}
+	}
+
+	return result.String()
+}
+
+func writeEscaped(result *strings.Builder, line string) {
+	for i := 0; i < len(line); i++ {
+		char := line[i]
+
+		switch char {
+		case '\b':
+			result.WriteString(`\b`)
+		case '\f':
+			result.WriteString(`\f`)
+		case '\r':
+			result.WriteString(`\r`)
+		case '\n':
+			result.WriteString(`\n`)
+		case '\t':
+			result.WriteString(`\t`)
+		case '\'':
+			result.WriteString(`\'`)
+		case '\\':
+			result.WriteString(`\\`)
+		case '/':
+			result.WriteString(`\/`)
+		case '-':
+			result.WriteString(`\-`)
+		default:
+			result.WriteByte(char)
+		}
+	}
+}
+
+// escapeValuesString escapes string to embed into single quoted literal of query or Values format
+// Only quotes, backslashes and control symbols are escaped unlike TabSeparated escaping of Escape
+func escapeValuesString(line string) string {
+	var result strings.Builder
+	result.Grow(len(line))
+
+	for i := 0; i < len(line); i++ {
+		char := line[i]
 
 		switch char {
-		case "\b":
-			result += "\\b"
-		case "\f":
-			result += "\\f"
-		case "\r":
-			result += "\\r"
-		case "\n":
-			result += "\\n"
-		case "\t":
-			result += "\\t"
-		case `'`:
-			result += `\'`
-		case `\`:
-			result += `\\`
-		case `/`:
-			result += `\/`
-		case `-`:
-			result += `\-`
+		case '\'', '\\':
+			result.WriteByte('\\')
+			result.WriteByte(char)
+		case '\b':
+			result.WriteString(`\b`)
+		case '\f':
+			result.WriteString(`\f`)
+		case '\r':
+			result.WriteString(`\r`)
+		case '\n':
+			result.WriteString(`\n`)
+		case '\t':
+			result.WriteString(`\t`)
+		case 0:
+			result.WriteString(`\0`)
 		default:
-			result += string(char)
+			result.WriteByte(char)
 		}
 	}
 
-	return result
+	return result.String()
 }
 
 // Unescape undoes escaping of special symbols
+// Unknown escape sequence \c is turned into c and all other bytes (including UTF-8 sequences) are kept as is
 func Unescape(line string) string {
-	result := ""
+	var result strings.Builder
+	result.Grow(len(line))
 
 	length := len(line)
-	for i := 0; i < length; i += 2 {
-		if i >= length-1 {
-			result += line[i : i+1]
-			break
+	for i := 0; i < length; i++ {
+		char := line[i]
+
+		if char != '\\' || i == length-1 {
+			result.WriteByte(char)
+
+			continue
 		}
 
-		pair := line[i : i+2]
-
-		switch pair {
-		case "\\b":
-			result += "\b"
-		case "\\f":
-			result += "\f"
-		case "\\r":
-			result += "\r"
-		case "\\n":
-			result += "\n"
-		case "\\t":
-			result += "\t"
-		case `\'`:
-			result += `'`
-		case `\\`:
-			result += `\`
-		case `\/`:
-			result += `/`
-		case `\-`:
-			result += `-`
+		i++
+
+		switch line[i] {
+		case 'b':
+			result.WriteByte('\b')
+		case 'f':
+			result.WriteByte('\f')
+		case 'r':
+			result.WriteByte('\r')
+		case 'n':
+			result.WriteByte('\n')
+		case 't':
+			result.WriteByte('\t')
+		case '0':
+			result.WriteByte(0)
 		default:
-			result += line[i : i+1]
-			i--
+			result.WriteByte(line[i])
+		}
+	}
+
+	return result.String()
+}
+
+// Quote returns SQL literal of value to embed into query
+// Strings are quoted and escaped, numbers are bare, time.Time is quoted as DateTime, bools are 1 or 0, nil is NULL,
+// slices and arrays are array literals and maps are map literals (pointers are dereferenced)
+func Quote(value interface{}) (string, error) {
+	literal, err := quoteValue(value)
+	if err != nil {
+		err = fmt.Errorf("can't quote value: %s", err.Error())
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return "", err
+	}
+
+	return literal, nil
+}
+
+func quoteValue(value interface{}) (string, error) {
+	switch v := indirect(value).(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return "'" + escapeValuesString(v) + "'", nil
+	case []byte:
+		return "'" + escapeValuesString(string(v)) + "'", nil
+	case bool:
+		if v {
+			return "1", nil
 		}
+
+		return "0", nil
+	case int:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int8:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int16:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05") + "'", nil
+	default:
+		return quoteComplex(v)
+	}
+}
+
+// quoteComplex returns array literal of slice or array and map literal of map
+func quoteComplex(value interface{}) (string, error) {
+	rv := reflect.ValueOf(value)
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		elements := make([]string, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			element, err := quoteValue(rv.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+
+			elements = append(elements, element)
+		}
+
+		return "[" + strings.Join(elements, ",") + "]", nil
+	case reflect.Map:
+		pairs := make([]string, 0, rv.Len())
+		for _, key := range rv.MapKeys() {
+			k, err := quoteValue(key.Interface())
+			if err != nil {
+				return "", err
+			}
+
+			v, err := quoteValue(rv.MapIndex(key).Interface())
+			if err != nil {
+				return "", err
+			}
+
+			pairs = append(pairs, k+":"+v)
+		}
+
+		// makes output stable
+		sort.Strings(pairs)
+
+		return "{" + strings.Join(pairs, ",") + "}", nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+// QuoteIdent returns identifier (e.g. column or table name) quoted with backticks
+func QuoteIdent(name string) string {
+	name = strings.Replace(name, "\\", "\\\\", -1)
+	name = strings.Replace(name, "`", "\\`", -1)
+
+	return "`" + name + "`"
+}
+
+// InValues returns parenthesized list of quoted and escaped values of slice or array to use in IN clause
+// e.g. []string{"a", "b"} becomes ('a','b')
+func InValues(values interface{}) (string, error) {
+	rv := reflect.ValueOf(values)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return "", fmt.Errorf("can't build IN list of type %T (slice or array is expected)", values)
+	}
+
+	if rv.Len() == 0 {
+		return "", errors.New("can't build IN list of empty values")
+	}
+
+	elements := make([]string, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		element, err := quoteValue(rv.Index(i).Interface())
+		if err != nil {
+			return "", fmt.Errorf("can't quote value %d: %s", i, err.Error())
+		}
+
+		elements = append(elements, element)
 	}
 
-	return result
+	return "(" + strings.Join(elements, ",") + ")", nil
 }
@@ -0,0 +1,153 @@
+package clickhouse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"plain", "abc", "abc"},
+		{"quote", "it's", `it\'s`},
+		{"backslash", `a\b`, `a\\b`},
+		{"control symbols", "a\tb\nc\rd\be\ff", `a\tb\nc\rd\be\ff`},
+		{"slash and dash", "a/b-c", `a\/b\-c`},
+		{"null byte", "a\x00b", "a\x00b"},
+		{"other control byte", "a\x01b", "a\x01b"},
+		{"utf-8", "привет, 世界", "привет, 世界"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Escape(test.value); got != test.want {
+				t.Errorf("Escape(%q) = %q; want %q", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestEscapeValuesString(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"plain", "abc", "abc"},
+		{"quote", "it's", `it\'s`},
+		{"backslash", `a\b`, `a\\b`},
+		{"control symbols", "a\tb\nc\rd\be\ff", `a\tb\nc\rd\be\ff`},
+		// unlike Escape slashes and dashes are kept as is
+		{"slash and dash", "a/b-c", "a/b-c"},
+		{"null byte", "a\x00b", `a\0b`},
+		{"other control byte", "a\x01b", "a\x01b"},
+		{"utf-8", "привет, 世界", "привет, 世界"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := escapeValuesString(test.value); got != test.want {
+				t.Errorf("escapeValuesString(%q) = %q; want %q", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	text := "a'b"
+
+	var nilPointer *int
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"nil", nil, "NULL"},
+		{"nil pointer", nilPointer, "NULL"},
+		{"pointer", &text, `'a\'b'`},
+		{"string", "it's", `'it\'s'`},
+		{"bytes", []byte("a\\b"), `'a\\b'`},
+		{"true", true, "1"},
+		{"false", false, "0"},
+		{"int", -42, "-42"},
+		{"int8", int8(-8), "-8"},
+		{"int64", int64(-9223372036854775808), "-9223372036854775808"},
+		{"uint8", uint8(255), "255"},
+		{"uint64", uint64(18446744073709551615), "18446744073709551615"},
+		{"float32", float32(1.5), "1.5"},
+		{"float64", 0.1, "0.1"},
+		{"big float64", 1e21, "1e+21"},
+		{"time", time.Date(2021, time.February, 3, 4, 5, 6, 7, time.UTC), "'2021-02-03 04:05:06'"},
+		{"slice", []int{1, 2, 3}, "[1,2,3]"},
+		{"empty slice", []string{}, "[]"},
+		{"array", [2]string{"a", "b"}, "['a','b']"},
+		{"nested slice", [][]interface{}{{1, "a"}, {nil}}, "[[1,'a'],[NULL]]"},
+		{"map with sorted keys", map[string]int{"b": 2, "a": 1, "c": 3}, "{'a':1,'b':2,'c':3}"},
+		{"map of slices", map[int][]string{2: {"x"}, 1: {}}, "{1:[],2:['x']}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Quote(test.value)
+			if err != nil {
+				t.Fatalf("Quote(%#v) returns error %s", test.value, err.Error())
+			}
+
+			if got != test.want {
+				t.Errorf("Quote(%#v) = %q; want %q", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestQuoteUnsupported(t *testing.T) {
+	for _, value := range []interface{}{struct{}{}, make(chan int), []interface{}{func() {}}} {
+		if _, err := Quote(value); err == nil {
+			t.Errorf("Quote(%T) doesn't return error", value)
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"plain", "abc", "abc"},
+		{"control symbols", `a\tb\nc\rd\be\ff`, "a\tb\nc\rd\be\ff"},
+		{"null byte", `a\0b`, "a\x00b"},
+		{"quote", `it\'s`, "it's"},
+		{"backslash", `a\\b`, `a\b`},
+		{"escaped backslash before letter", `a\\n`, `a\n`},
+		{"unknown sequence", `\/\-\x`, "/-x"},
+		{"trailing backslash", `abc\`, `abc\`},
+		{"single backslash", `\`, `\`},
+		{"odd length", `ab\nc`, "ab\nc"},
+		{"utf-8", `привет\tмир`, "привет\tмир"},
+		{"escaped utf-8", `\п`, "п"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Unescape(test.value); got != test.want {
+				t.Errorf("Unescape(%q) = %q; want %q", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnescapeOfEscape(t *testing.T) {
+	for _, value := range []string{"", "a'b\\c\td\ne/f-g", "привет, 世界\r\n", "\b\f"} {
+		if got := Unescape(Escape(value)); got != value {
+			t.Errorf("Unescape(Escape(%q)) = %q", value, got)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package clickhouse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// explainKinds are kinds of EXPLAIN supported by Explain
+var explainKinds = map[string]bool{"PLAN": true, "PIPELINE": true, "SYNTAX": true, "ESTIMATE": true}
+
+// Explain returns lines of EXPLAIN of passed kind (PLAN, PIPELINE, SYNTAX or ESTIMATE) for query without executing of it
+// Rows of multi-column output (e.g. ESTIMATE) are returned as lines of tab separated values
+func (conn *Conn) Explain(query string, kind string) ([]string, error) {
+	kind = strings.ToUpper(strings.TrimSpace(kind))
+	if !explainKinds[kind] {
+		err := fmt.Errorf("kind `%s` of EXPLAIN isn't supported (use PLAN, PIPELINE, SYNTAX or ESTIMATE)", kind)
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return nil, err
+	}
+
+	query = setFormat(fmt.Sprintf("EXPLAIN %s %s", kind, strings.TrimSpace(query)), TSVWithNames)
+
+	iter, err := conn.Fetch(query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	var lines []string
+	for iter.Next() {
+		columns := iter.ColumnNames()
+
+		values := make([]string, 0, len(columns))
+		for _, column := range columns {
+			value, err := iter.Result.String(column)
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, value)
+		}
+
+		lines = append(lines, strings.Join(values, "\t"))
+	}
+
+	return lines, iter.Err()
+}
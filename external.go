@@ -0,0 +1,93 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+)
+
+// ExternalData describes client-side data which is sent with query as temporary table
+type ExternalData struct {
+	// Name is name of the temporary table used in query (e.g. WHERE id IN ids)
+	Name string
+	// Structure is list of columns and their types (e.g. "id UInt64, name String")
+	Structure string
+	// Format is format of data (TabSeparated by default)
+	Format Format
+	// Data is data of the table
+	Data io.Reader
+}
+
+// FetchWithExternal executes new query with external data sent as temporary tables and fetches it
+// The data is streamed in multipart form so the query isn't retried (see Attempts)
+func (conn *Conn) FetchWithExternal(ctx context.Context, query string, ext []ExternalData) (Iter, error) {
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	if len(ext) == 0 {
+		err := errors.New("there is no external data")
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return Iter{conn: conn}, err
+	}
+
+	settings := make(map[string]string, len(ext)*2)
+	for index, data := range ext {
+		if len(data.Name) == 0 || len(data.Structure) == 0 || data.Data == nil {
+			err := fmt.Errorf("external data %d has to have name, structure and data", index)
+
+			message := fmt.Sprintf("Catch error %s", err.Error())
+			cfg.logger.error(message)
+
+			return Iter{conn: conn}, err
+		}
+
+		format := data.Format
+		if len(format) == 0 {
+			format = TSV
+		}
+
+		settings[data.Name+"_structure"] = data.Structure
+		settings[data.Name+"_format"] = string(format)
+	}
+
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+
+	go func() {
+		writer.CloseWithError(writeExternal(form, ext))
+	}()
+
+	// stops writing of the form if the server responded without reading the whole body
+	defer reader.Close()
+
+	ctx = withContentType(WithSettings(ctx, settings), form.FormDataContentType())
+
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	format := conn.fetchFormat(query)
+
+	return conn.openIter(ctx, setFormat(query, format), format, reader)
+}
+
+func writeExternal(form *multipart.Writer, ext []ExternalData) error {
+	for _, data := range ext {
+		part, err := form.CreateFormFile(data.Name, data.Name)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(part, data.Data)
+		if err != nil {
+			return fmt.Errorf("can't send external data `%s`: %s", data.Name, err.Error())
+		}
+	}
+
+	return form.Close()
+}
@@ -1,13 +1,16 @@
 package clickhouse
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
-//TODO maybe need to add GetArray<Type> func(column string) (Type, error) where Type is all listen types above
-
 // Columns returns columns list
 func (result Result) Columns() (columns []string) {
 	for column := range result.data {
@@ -17,20 +20,72 @@ func (result Result) Columns() (columns []string) {
 	return columns
 }
 
+// value returns raw value of column
+// Name is matched case-insensitively if exact name isn't found and lookup is case-insensitive (see CaseInsensitiveColumns)
+func (result Result) value(column string) (string, bool) {
+	value, ok := result.data[column]
+	if ok || result.index == nil {
+		return value, ok
+	}
+
+	name, ok := result.index[strings.ToLower(column)]
+	if !ok {
+		return "", false
+	}
+
+	value, ok = result.data[name]
+
+	return value, ok
+}
+
+// lowerIndex maps lowercased names of columns to their names
+// Names which differ only in case are ambiguous so they are mapped to empty name and found only by exact name
+func lowerIndex(data map[string]string) map[string]string {
+	index := make(map[string]string, len(data))
+	for column := range data {
+		lower := strings.ToLower(column)
+		if _, ok := index[lower]; ok {
+			index[lower] = ""
+		} else {
+			index[lower] = column
+		}
+	}
+
+	return index
+}
+
 // Exist returns true if field is exist or false
 func (result Result) Exist(column string) bool {
 	cfg.logger.debug(fmt.Sprintf("Try to check if exist by `%s`", column))
 
-	_, ok := result.data[column]
+	_, ok := result.value(column)
 
 	return ok
 }
 
-// String returns value of string
-func (result Result) String(column string) (value string, err error) {
+// IsNull returns true if value of column is NULL (absent columns aren't NULL)
+func (result Result) IsNull(column string) bool {
+	value, ok := result.value(column)
+
+	return ok && value == `\N`
+}
+
+// String returns value of string with undone escaping of TabSeparated formats unless RawFields is on
+// NULL is returned as \N
+func (result Result) String(column string) (string, error) {
+	value, err := result.raw(column)
+	if err != nil || !result.escaped || value == `\N` {
+		return value, err
+	}
+
+	return Unescape(value), nil
+}
+
+// raw returns value as server sent it (e.g. to parse literals of arrays and tuples which have own escaping)
+func (result Result) raw(column string) (value string, err error) {
 	cfg.logger.debug(fmt.Sprintf("Try to get value by `%s`", column))
 
-	value, ok := result.data[column]
+	value, ok := result.value(column)
 	if !ok {
 		err = fmt.Errorf("can't get value by `%s`", column)
 
@@ -56,6 +111,89 @@ func (result Result) Bytes(column string) (bytes []byte, err error) {
 	return
 }
 
+// JSON unmarshals JSON stored in string value into dest
+func (result Result) JSON(column string, dest interface{}) error {
+	value, err := result.String(column)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal([]byte(value), dest)
+	if err != nil {
+		err = fmt.Errorf("can't unmarshal JSON of `%s`: %s", column, err.Error())
+
+		cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+		return err
+	}
+
+	return nil
+}
+
+// JSONArray unmarshals JSON array stored in string value into slice pointed at by dest
+func (result Result) JSONArray(column string, dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		err := fmt.Errorf("can't unmarshal JSON array of `%s` into %T (pointer to slice is expected)", column, dest)
+
+		cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+		return err
+	}
+
+	value, err := result.String(column)
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(strings.TrimSpace(value), "[") {
+		err = fmt.Errorf("value of `%s` isn't JSON array: %s", column, cutOffQuery(value, 100))
+
+		cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+		return err
+	}
+
+	return result.JSON(column, dest)
+}
+
+// FixedString returns value of FixedString without trailing null bytes of padding
+func (result Result) FixedString(column string) (value string, err error) {
+	value, err = result.String(column)
+	if err != nil {
+		return
+	}
+
+	return trimNullPadding(value), nil
+}
+
+// trimNullPadding trims trailing null bytes which are raw (JSON formats) or escaped as \0 (TabSeparated formats)
+func trimNullPadding(value string) string {
+	for {
+		if strings.HasSuffix(value, "\x00") {
+			value = value[:len(value)-1]
+
+			continue
+		}
+
+		if !strings.HasSuffix(value, `\0`) {
+			return value
+		}
+
+		// the backslash mustn't be escaped itself
+		slashes := 0
+		for i := len(value) - 2; i >= 0 && value[i] == '\\'; i-- {
+			slashes++
+		}
+
+		if slashes%2 == 0 {
+			return value
+		}
+
+		value = value[:len(value)-2]
+	}
+}
+
 func (result Result) getUInt(column string, bitSize int) (ui64 uint64, err error) {
 	value, err := result.String(column)
 	if err != nil {
@@ -64,6 +202,10 @@ func (result Result) getUInt(column string, bitSize int) (ui64 uint64, err error
 		return
 	}
 
+	if len(value) == 0 && result.isEmptyNumericZero() {
+		return 0, nil
+	}
+
 	ui64, err = strconv.ParseUint(value, 10, bitSize)
 	if err != nil {
 		err = fmt.Errorf("can't convert value %s to uint%d: %s", value, bitSize, err.Error())
@@ -76,8 +218,17 @@ func (result Result) getUInt(column string, bitSize int) (ui64 uint64, err error
 	return
 }
 
-// Bool returns value as bool
+// Bool returns value as bool (1/0 or true/false)
 func (result Result) Bool(column string) (f bool, err error) {
+	value, _ := result.value(column)
+
+	switch value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
 	i, err := result.getUInt(column, 8)
 	if err != nil {
 		return
@@ -122,6 +273,10 @@ func (result Result) getInt(column string, bitSize int) (i64 int64, err error) {
 		return 0, err
 	}
 
+	if len(value) == 0 && result.isEmptyNumericZero() {
+		return 0, nil
+	}
+
 	i64, err = strconv.ParseInt(value, 10, bitSize)
 	if err != nil {
 		err := fmt.Errorf("can't convert value %s to int%d: %s", value, bitSize, err.Error())
@@ -170,18 +325,55 @@ func (result Result) getFloat(column string, bitSize int) (f64 float64, err erro
 		return 0, err
 	}
 
-	f64, err = strconv.ParseFloat(value, bitSize)
-	if err != nil {
-		err := fmt.Errorf("can't convert value %s to float%d: %s", value, bitSize, err.Error())
+	switch value {
+	case "-nan", "+nan":
+		f64 = math.NaN()
+	case "":
+		if result.isEmptyNumericZero() {
+			return 0, nil
+		}
 
-		cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+		fallthrough
+	default:
+		f64, err = strconv.ParseFloat(value, bitSize)
+		if err != nil {
+			err := fmt.Errorf("can't convert value %s to float%d: %s", value, bitSize, err.Error())
 
-		return 0, err
+			cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+			return 0, err
+		}
+	}
+
+	if math.IsNaN(f64) || math.IsInf(f64, 0) {
+		switch result.nonFiniteMode() {
+		case NonFiniteError:
+			err := fmt.Errorf("value %s of `%s` isn't finite float", value, column)
+
+			cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+			return 0, err
+		case NonFiniteZero:
+			return 0, nil
+		}
 	}
 
 	return f64, nil
 }
 
+func (result Result) nonFiniteMode() NonFiniteMode {
+	if result.conn == nil {
+		return NonFiniteAllow
+	}
+
+	return NonFiniteMode(atomic.LoadInt32(&result.conn.nonFinite))
+}
+
+// isEmptyNumericZero checks if empty values of numeric columns are read as zero (see EmptyNumericAsZero)
+func (result Result) isEmptyNumericZero() bool {
+	return result.conn != nil && atomic.LoadInt32(&result.conn.emptyAsZero) == 1
+}
+
 // Float32 returns value as float32
 func (result Result) Float32(column string) (f32 float32, err error) {
 	f, err := result.getFloat(column, 32)
@@ -217,7 +409,43 @@ func (result Result) Date(column string) (t time.Time, err error) {
 	return t, nil
 }
 
-// DateTime returns value as datetime
+var (
+	// date32Min and date32Max are bounds of Date32 type
+	date32Min = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+	date32Max = time.Date(2299, time.December, 31, 0, 0, 0, 0, time.UTC)
+)
+
+// Date32 returns value of Date32 (extended range from 1900-01-01 to 2299-12-31) as date
+func (result Result) Date32(column string) (t time.Time, err error) {
+	t, err = result.Date(column)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	err = checkDate32(t)
+	if err != nil {
+		cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+		return time.Time{}, err
+	}
+
+	return t, nil
+}
+
+// checkDate32 checks that date of time is in range of Date32 type
+func checkDate32(t time.Time) error {
+	year, month, day := t.Date()
+
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if date.Before(date32Min) || date.After(date32Max) {
+		return fmt.Errorf("date %s is out of range of Date32 [%s, %s]",
+			date.Format("2006-01-02"), date32Min.Format("2006-01-02"), date32Max.Format("2006-01-02"))
+	}
+
+	return nil
+}
+
+// DateTime returns value as datetime in time zone reported by server (see Iter.Timezone) or UTC if it's unknown
 func (result Result) DateTime(column string) (t time.Time, err error) {
 	value, err := result.String(column)
 	if err != nil {
@@ -226,7 +454,12 @@ func (result Result) DateTime(column string) (t time.Time, err error) {
 		return time.Time{}, err
 	}
 
-	t, err = time.Parse("2006-01-02 15:04:05", value)
+	location := result.location
+	if location == nil {
+		location = time.UTC
+	}
+
+	t, err = time.ParseInLocation("2006-01-02 15:04:05", value, location)
 	if err != nil {
 		err := fmt.Errorf("can't convert value %s to datetime: %s", value, err.Error())
 
@@ -237,3 +470,246 @@ func (result Result) DateTime(column string) (t time.Time, err error) {
 
 	return t, nil
 }
+
+// NullableDate returns value of Nullable(Date) or nil for NULL
+func (result Result) NullableDate(column string) (*time.Time, error) {
+	if value, _ := result.value(column); value == `\N` {
+		return nil, nil
+	}
+
+	t, err := result.Date(column)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// NullableDate32 returns value of Nullable(Date32) or nil for NULL
+func (result Result) NullableDate32(column string) (*time.Time, error) {
+	if value, _ := result.value(column); value == `\N` {
+		return nil, nil
+	}
+
+	t, err := result.Date32(column)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// NullableDateTime returns value of Nullable(DateTime) or nil for NULL
+func (result Result) NullableDateTime(column string) (*time.Time, error) {
+	if value, _ := result.value(column); value == `\N` {
+		return nil, nil
+	}
+
+	t, err := result.DateTime(column)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// Duration returns numeric value multiplied by unit as duration
+func (result Result) Duration(column string, unit time.Duration) (d time.Duration, err error) {
+	i, err := result.getInt(column, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(i) * unit, nil
+}
+
+// StringDefault returns value as string or def if value is absent or can't be converted
+func (result Result) StringDefault(column string, def string) string {
+	value, err := result.String(column)
+	if err != nil {
+		return def
+	}
+
+	return value
+}
+
+// BoolDefault returns value as bool or def if value is absent or can't be converted
+func (result Result) BoolDefault(column string, def bool) bool {
+	f, err := result.Bool(column)
+	if err != nil {
+		return def
+	}
+
+	return f
+}
+
+// UInt8Default returns value as uint8 or def if value is absent or can't be converted
+func (result Result) UInt8Default(column string, def uint8) uint8 {
+	ui8, err := result.UInt8(column)
+	if err != nil {
+		return def
+	}
+
+	return ui8
+}
+
+// UInt16Default returns value as uint16 or def if value is absent or can't be converted
+func (result Result) UInt16Default(column string, def uint16) uint16 {
+	ui16, err := result.UInt16(column)
+	if err != nil {
+		return def
+	}
+
+	return ui16
+}
+
+// UInt32Default returns value as uint32 or def if value is absent or can't be converted
+func (result Result) UInt32Default(column string, def uint32) uint32 {
+	ui32, err := result.UInt32(column)
+	if err != nil {
+		return def
+	}
+
+	return ui32
+}
+
+// UInt64Default returns value as uint64 or def if value is absent or can't be converted
+func (result Result) UInt64Default(column string, def uint64) uint64 {
+	ui64, err := result.UInt64(column)
+	if err != nil {
+		return def
+	}
+
+	return ui64
+}
+
+// Int8Default returns value as int8 or def if value is absent or can't be converted
+func (result Result) Int8Default(column string, def int8) int8 {
+	i8, err := result.Int8(column)
+	if err != nil {
+		return def
+	}
+
+	return i8
+}
+
+// Int16Default returns value as int16 or def if value is absent or can't be converted
+func (result Result) Int16Default(column string, def int16) int16 {
+	i16, err := result.Int16(column)
+	if err != nil {
+		return def
+	}
+
+	return i16
+}
+
+// Int32Default returns value as int32 or def if value is absent or can't be converted
+func (result Result) Int32Default(column string, def int32) int32 {
+	i32, err := result.Int32(column)
+	if err != nil {
+		return def
+	}
+
+	return i32
+}
+
+// Int64Default returns value as int64 or def if value is absent or can't be converted
+func (result Result) Int64Default(column string, def int64) int64 {
+	i64, err := result.Int64(column)
+	if err != nil {
+		return def
+	}
+
+	return i64
+}
+
+// Float32Default returns value as float32 or def if value is absent or can't be converted
+func (result Result) Float32Default(column string, def float32) float32 {
+	f32, err := result.Float32(column)
+	if err != nil {
+		return def
+	}
+
+	return f32
+}
+
+// Float64Default returns value as float64 or def if value is absent or can't be converted
+func (result Result) Float64Default(column string, def float64) float64 {
+	f64, err := result.Float64(column)
+	if err != nil {
+		return def
+	}
+
+	return f64
+}
+
+// ScanMap returns values converted to Go types according to map of column types
+// (e.g. UInt32 becomes uint32, DateTime becomes time.Time, NULL of Nullable becomes nil)
+// Columns of unknown types are returned as strings
+func (result Result) ScanMap(types map[string]string) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(result.data))
+
+	for column := range result.data {
+		value, err := result.scanType(column, types[column])
+		if err != nil {
+			return nil, err
+		}
+
+		values[column] = value
+	}
+
+	return values, nil
+}
+
+func (result Result) scanType(column, typ string) (interface{}, error) {
+	typ = strings.TrimSpace(typ)
+
+	if strings.HasPrefix(typ, "LowCardinality(") && strings.HasSuffix(typ, ")") {
+		typ = typ[len("LowCardinality(") : len(typ)-1]
+	}
+
+	if strings.HasPrefix(typ, "Nullable(") && strings.HasSuffix(typ, ")") {
+		if value, _ := result.value(column); value == `\N` {
+			return nil, nil
+		}
+
+		typ = typ[len("Nullable(") : len(typ)-1]
+	}
+
+	if index := strings.Index(typ, "("); index > 0 {
+		typ = typ[:index]
+	}
+
+	switch typ {
+	case "Bool":
+		return result.Bool(column)
+	case "UInt8":
+		return result.UInt8(column)
+	case "UInt16":
+		return result.UInt16(column)
+	case "UInt32":
+		return result.UInt32(column)
+	case "UInt64":
+		return result.UInt64(column)
+	case "Int8":
+		return result.Int8(column)
+	case "Int16":
+		return result.Int16(column)
+	case "Int32":
+		return result.Int32(column)
+	case "Int64":
+		return result.Int64(column)
+	case "Float32":
+		return result.Float32(column)
+	case "Float64":
+		return result.Float64(column)
+	case "Date":
+		return result.Date(column)
+	case "Date32":
+		return result.Date32(column)
+	case "DateTime":
+		return result.DateTime(column)
+	default:
+		return result.String(column)
+	}
+}
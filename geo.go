@@ -0,0 +1,149 @@
+package clickhouse
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Point returns value of Point as pair of coordinates
+func (result Result) Point(column string) (point [2]float64, err error) {
+	value, err := result.raw(column)
+	if err != nil {
+		return
+	}
+
+	point, err = parsePoint(value)
+	if err != nil {
+		err = fmt.Errorf("can't convert value %s to point: %s", value, err.Error())
+
+		cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+	}
+
+	return
+}
+
+// Ring returns value of Ring as list of points
+func (result Result) Ring(column string) (ring [][2]float64, err error) {
+	value, err := result.raw(column)
+	if err != nil {
+		return
+	}
+
+	ring, err = parseRing(value)
+	if err != nil {
+		err = fmt.Errorf("can't convert value %s to ring: %s", value, err.Error())
+
+		cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+	}
+
+	return
+}
+
+// Polygon returns value of Polygon as list of rings (the first is outer one and the rest are holes)
+func (result Result) Polygon(column string) (polygon [][][2]float64, err error) {
+	value, err := result.raw(column)
+	if err != nil {
+		return
+	}
+
+	polygon, err = parsePolygon(value)
+	if err != nil {
+		err = fmt.Errorf("can't convert value %s to polygon: %s", value, err.Error())
+
+		cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+	}
+
+	return
+}
+
+// MultiPolygon returns value of MultiPolygon as list of polygons
+func (result Result) MultiPolygon(column string) (multiPolygon [][][][2]float64, err error) {
+	value, err := result.raw(column)
+	if err != nil {
+		return
+	}
+
+	elements, err := splitLiteral(value, '[', ']')
+	if err == nil {
+		multiPolygon = make([][][][2]float64, 0, len(elements))
+		for _, element := range elements {
+			var polygon [][][2]float64
+			polygon, err = parsePolygon(element)
+			if err != nil {
+				break
+			}
+
+			multiPolygon = append(multiPolygon, polygon)
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("can't convert value %s to multipolygon: %s", value, err.Error())
+
+		cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+		return nil, err
+	}
+
+	return
+}
+
+func parsePoint(value string) (point [2]float64, err error) {
+	elements, err := splitLiteral(value, '(', ')')
+	if err != nil {
+		return
+	}
+
+	if len(elements) != 2 {
+		return point, fmt.Errorf("point has %d coordinates instead of 2", len(elements))
+	}
+
+	for i, element := range elements {
+		point[i], err = strconv.ParseFloat(element, 64)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func parseRing(value string) (ring [][2]float64, err error) {
+	elements, err := splitLiteral(value, '[', ']')
+	if err != nil {
+		return
+	}
+
+	ring = make([][2]float64, 0, len(elements))
+	for _, element := range elements {
+		var point [2]float64
+		point, err = parsePoint(element)
+		if err != nil {
+			return nil, err
+		}
+
+		ring = append(ring, point)
+	}
+
+	return
+}
+
+func parsePolygon(value string) (polygon [][][2]float64, err error) {
+	elements, err := splitLiteral(value, '[', ']')
+	if err != nil {
+		return
+	}
+
+	polygon = make([][][2]float64, 0, len(elements))
+	for _, element := range elements {
+		var ring [][2]float64
+		ring, err = parseRing(element)
+		if err != nil {
+			return nil, err
+		}
+
+		polygon = append(polygon, ring)
+	}
+
+	return
+}
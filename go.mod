@@ -1,5 +1,3 @@
 module github.com/leprosus/golang-clickhouse
 
-go 1.14
-
-require github.com/leprosus/golang-composer v1.0.0
+go 1.18
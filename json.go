@@ -0,0 +1,118 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// parseJSONRow parses one JSONEachRow line into text values and columns list in order of the line
+// Strings are unquoted, null becomes \N, nested arrays and objects are kept as JSON text
+func parseJSONRow(line []byte) (map[string]string, []string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't parse JSON row: %s", err.Error())
+	}
+
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, nil, fmt.Errorf("JSON row isn't object: %s", string(line))
+	}
+
+	var (
+		data    = make(map[string]string)
+		columns []string
+	)
+
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't parse JSON row: %s", err.Error())
+		}
+
+		column, ok := token.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("JSON row has invalid key %v", token)
+		}
+
+		var raw json.RawMessage
+		err = decoder.Decode(&raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't parse JSON value of `%s`: %s", column, err.Error())
+		}
+
+		data[column] = jsonText(raw)
+		columns = append(columns, column)
+	}
+
+	return data, columns, nil
+}
+
+func jsonText(raw json.RawMessage) string {
+	switch {
+	case len(raw) == 0:
+		return ""
+	case string(raw) == "null":
+		return `\N`
+	case raw[0] == '"':
+		var text string
+		if json.Unmarshal(raw, &text) != nil {
+			return string(raw)
+		}
+
+		return text
+	default:
+		return string(raw)
+	}
+}
+
+// FetchJSONStream executes new query with JSONEachRow format and sends decoded rows to out channel as they arrive
+// Numbers are sent as json.Number so big UInt64 and Int64 values don't lose precision as float64
+// The channel is closed when all rows are sent or on error
+func (conn *Conn) FetchJSONStream(ctx context.Context, query string, out chan<- map[string]interface{}) error {
+	defer close(out)
+
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	reader, _, err := conn.doQuery(ctx, setFormat(query, JSONEachRow), nil)
+	if err != nil {
+		return err
+	}
+
+	defer reader.Close()
+
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+
+	for {
+		row := make(map[string]interface{})
+
+		err = decoder.Decode(&row)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			message = fmt.Sprintf("Catch error %s", err.Error())
+			cfg.logger.error(message)
+
+			return err
+		}
+
+		select {
+		case out <- row:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	cfg.logger.debug("The query is fetched")
+
+	return nil
+}
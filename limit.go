@@ -0,0 +1,137 @@
+package clickhouse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// queryWord is keyword or identifier of the outermost level of query
+type queryWord struct {
+	word  string
+	start int
+}
+
+// topLevelWords returns uppercased words of query outside of brackets, quotes and comments
+// and end of the last code of the query (trailing comments, spaces and semicolons are ignored)
+func topLevelWords(query string) ([]queryWord, int) {
+	var (
+		words []queryWord
+		quote byte
+		depth int
+		end   int
+	)
+
+	for i := 0; i < len(query); i++ {
+		char := query[i]
+
+		switch {
+		case quote != 0:
+			if char == '\\' {
+				i++
+			} else if char == quote {
+				quote = 0
+			}
+
+			end = i + 1
+
+			continue
+		case char == '-' && strings.HasPrefix(query[i:], "--"):
+			if index := strings.IndexByte(query[i:], '\n'); index >= 0 {
+				i += index
+
+				continue
+			}
+
+			return words, end
+		case char == '/' && strings.HasPrefix(query[i:], "/*"):
+			index := strings.Index(query[i:], "*/")
+			if index < 0 {
+				return words, end
+			}
+
+			i += index + 1
+
+			continue
+		}
+
+		switch {
+		case char == '\'' || char == '"' || char == '`':
+			quote = char
+		case char == '(' || char == '[' || char == '{':
+			depth++
+		case char == ')' || char == ']' || char == '}':
+			depth--
+		case isWordChar(char) && (char < '0' || char > '9'):
+			start := i
+			for i+1 < len(query) && isWordChar(query[i+1]) {
+				i++
+			}
+
+			if depth == 0 {
+				words = append(words, queryWord{word: strings.ToUpper(query[start : i+1]), start: start})
+			}
+		case isWordChar(char):
+			// numbers aren't words
+			for i+1 < len(query) && isWordChar(query[i+1]) {
+				i++
+			}
+		}
+
+		if char != ' ' && char != '\t' && char != '\r' && char != '\n' && char != ';' {
+			end = i + 1
+		}
+	}
+
+	return words, end
+}
+
+func isWordChar(char byte) bool {
+	return char == '_' || char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9'
+}
+
+// injectLimit adds LIMIT clause into SELECT query without the outermost LIMIT (LIMIT BY isn't counted)
+// The clause is added before OFFSET, SETTINGS and FORMAT clauses. It returns false if the query is kept as is
+func injectLimit(query string, limit int) (string, bool) {
+	words, end := topLevelWords(query)
+	if len(words) == 0 || words[0].word != "SELECT" && words[0].word != "WITH" {
+		return query, false
+	}
+
+	for index := 0; index < len(words); index++ {
+		word := words[index]
+
+		switch word.word {
+		case "UNION", "INTERSECT", "EXCEPT":
+			// LIMIT of the last query of UNION doesn't limit the whole result
+			return query, false
+		case "LIMIT":
+			next := index + 1
+			if next < len(words) && words[next].word == "OFFSET" {
+				next++
+			}
+
+			if next >= len(words) || words[next].word != "BY" {
+				return query, false
+			}
+
+			// OFFSET of LIMIT BY isn't OFFSET of the query
+			index = next
+		case "FETCH":
+			// OFFSET ... FETCH limits result as LIMIT
+			return query, false
+		case "OFFSET", "SETTINGS", "FORMAT":
+			if word.start < end {
+				end = word.start
+			}
+		}
+	}
+
+	head := strings.TrimRight(query[:end], " \t\r\n")
+
+	tail := strings.TrimLeft(query[end:], " \t\r\n")
+	if len(tail) > 0 {
+		tail = " " + tail
+	}
+
+	return fmt.Sprintf("%s LIMIT %d%s", head, limit, tail), true
+}
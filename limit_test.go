@@ -0,0 +1,45 @@
+package clickhouse
+
+import "testing"
+
+func TestInjectLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		result string
+		ok     bool
+	}{
+		{"plain select", "SELECT * FROM t", "SELECT * FROM t LIMIT 10", true},
+		{"with", "WITH 1 AS x SELECT x FROM t", "WITH 1 AS x SELECT x FROM t LIMIT 10", true},
+		{"lowercase", "select * from t", "select * from t LIMIT 10", true},
+		{"not select", "INSERT INTO t SELECT * FROM s", "INSERT INTO t SELECT * FROM s", false},
+		{"existing limit", "SELECT * FROM t LIMIT 5", "SELECT * FROM t LIMIT 5", false},
+		{"existing limit with offset", "SELECT * FROM t LIMIT 5 OFFSET 2", "SELECT * FROM t LIMIT 5 OFFSET 2", false},
+		{"subquery limit", "SELECT * FROM (SELECT * FROM t LIMIT 5)", "SELECT * FROM (SELECT * FROM t LIMIT 5) LIMIT 10", true},
+		{"limit by", "SELECT * FROM t LIMIT 1 BY id", "SELECT * FROM t LIMIT 1 BY id LIMIT 10", true},
+		{"limit offset by", "SELECT * FROM t LIMIT 1 OFFSET 1 BY id", "SELECT * FROM t LIMIT 1 OFFSET 1 BY id LIMIT 10", true},
+		{"limit by and limit", "SELECT * FROM t LIMIT 1 BY id LIMIT 5", "SELECT * FROM t LIMIT 1 BY id LIMIT 5", false},
+		{"union", "SELECT 1 UNION ALL SELECT 2", "SELECT 1 UNION ALL SELECT 2", false},
+		{"intersect", "SELECT 1 INTERSECT SELECT 1", "SELECT 1 INTERSECT SELECT 1", false},
+		{"offset", "SELECT * FROM t OFFSET 5", "SELECT * FROM t LIMIT 10 OFFSET 5", true},
+		{"offset fetch", "SELECT * FROM t ORDER BY id OFFSET 5 ROWS FETCH FIRST 3 ROWS ONLY", "SELECT * FROM t ORDER BY id OFFSET 5 ROWS FETCH FIRST 3 ROWS ONLY", false},
+		{"settings", "SELECT * FROM t SETTINGS max_threads = 1", "SELECT * FROM t LIMIT 10 SETTINGS max_threads = 1", true},
+		{"format", "SELECT * FROM t FORMAT JSONEachRow", "SELECT * FROM t LIMIT 10 FORMAT JSONEachRow", true},
+		{"settings and format", "SELECT * FROM t SETTINGS max_threads = 1 FORMAT CSV", "SELECT * FROM t LIMIT 10 SETTINGS max_threads = 1 FORMAT CSV", true},
+		{"limit in string", "SELECT 'LIMIT 5' FROM t", "SELECT 'LIMIT 5' FROM t LIMIT 10", true},
+		{"limit in identifier", "SELECT `LIMIT` FROM t", "SELECT `LIMIT` FROM t LIMIT 10", true},
+		{"trailing line comment", "SELECT * FROM t -- all rows", "SELECT * FROM t LIMIT 10 -- all rows", true},
+		{"trailing block comment", "SELECT * FROM t /* all rows */", "SELECT * FROM t LIMIT 10 /* all rows */", true},
+		{"limit in comment", "SELECT * FROM t -- LIMIT 5", "SELECT * FROM t LIMIT 10 -- LIMIT 5", true},
+		{"trailing line comment with newline", "SELECT * FROM t -- all rows\n", "SELECT * FROM t LIMIT 10 -- all rows\n", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, ok := injectLimit(test.query, 10)
+			if result != test.result || ok != test.ok {
+				t.Errorf("injectLimit(%q) = %q, %v; want %q, %v", test.query, result, ok, test.result, test.ok)
+			}
+		})
+	}
+}
@@ -1,58 +1,190 @@
 package clickhouse
 
 import (
+	"context"
 	"fmt"
-	composer "github.com/leprosus/golang-composer"
 	"sync"
-	"sync/atomic"
+	"time"
 )
 
+// Limiter limits amount of simultaneous requests of connection and its clones
+// Condition variable is used instead of channel semaphore because the limit can be changed at any time
+// Nil limiter (e.g. of connection which isn't created by New) doesn't limit requests
 type Limiter struct {
 	once sync.Once
+	mux  sync.Mutex
+	cond *sync.Cond
 
 	maxRequests     uint32
-	requestsCounter int32
-	queue           chan int32
+	requestsCounter uint32
+	closed          bool
+
+	qps      float64
+	nextSlot time.Time
 }
 
 // MaxRequests sets requests limitation (zero is limitation off)
 func (lim *Limiter) MaxRequests(limit int) {
-	atomic.StoreUint32(&lim.maxRequests, uint32(limit))
+	if lim == nil {
+		cfg.logger.warn("Catch warning limiter isn't initialized so limit isn't set (create connection with New)")
+
+		return
+	}
+
+	lim.init()
+
+	lim.mux.Lock()
+	lim.maxRequests = uint32(limit)
+	lim.mux.Unlock()
+
+	// waiters have to check the new limit
+	lim.cond.Broadcast()
 
 	message := fmt.Sprintf("Set max request pool = %d", limit)
 	cfg.logger.debug(message)
 }
 
-func (lim *Limiter) initQueue() {
-	lim.queue = make(chan int32)
+// RateLimit limits amount of queries per second (zero turns limitation off)
+// Queries are spaced evenly so there are no bursts and waiting for the turn stops when context of query is done
+func (lim *Limiter) RateLimit(qps float64) {
+	if lim == nil {
+		cfg.logger.warn("Catch warning limiter isn't initialized so limit isn't set (create connection with New)")
 
-	go func() {
-		for step := range lim.queue {
-			atomic.AddInt32(&lim.requestsCounter, step)
+		return
+	}
 
-			if atomic.LoadUint32(&lim.maxRequests) > 0 {
-				if atomic.LoadUint32(&lim.maxRequests) <= uint32(atomic.LoadInt32(&lim.requestsCounter)) {
-					composer.GetComposer().Pause()
-				} else {
-					composer.GetComposer().Play()
-				}
-			} else {
-				composer.GetComposer().Play()
-			}
-		}
-	}()
+	lim.init()
+
+	lim.mux.Lock()
+	lim.qps = qps
+	lim.nextSlot = time.Time{}
+	lim.mux.Unlock()
+
+	message := fmt.Sprintf("Set rate limit = %g queries per second", qps)
+	cfg.logger.debug(message)
+}
+
+func (lim *Limiter) init() {
+	lim.once.Do(func() {
+		lim.cond = sync.NewCond(&lim.mux)
+	})
+}
+
+// isFull checks if the limit is reached (the mutex has to be locked)
+func (lim *Limiter) isFull() bool {
+	return !lim.closed && lim.maxRequests > 0 && lim.requestsCounter >= lim.maxRequests
 }
 
 func (lim *Limiter) increase() {
-	lim.once.Do(lim.initQueue)
-	lim.queue <- 1
+	if lim == nil {
+		return
+	}
+
+	lim.init()
+
+	lim.mux.Lock()
+	for lim.isFull() {
+		lim.cond.Wait()
+	}
+	lim.requestsCounter++
+	lim.mux.Unlock()
 }
 
 func (lim *Limiter) reduce() {
-	lim.once.Do(lim.initQueue)
-	lim.queue <- -1
+	if lim == nil {
+		return
+	}
+
+	lim.init()
+
+	lim.mux.Lock()
+	if lim.requestsCounter > 0 {
+		lim.requestsCounter--
+	}
+	lim.mux.Unlock()
+
+	lim.cond.Broadcast()
+}
+
+// close releases all waiting requests
+func (lim *Limiter) close() {
+	if lim == nil {
+		return
+	}
+
+	lim.init()
+
+	lim.mux.Lock()
+	lim.closed = true
+	lim.mux.Unlock()
+
+	lim.cond.Broadcast()
+}
+
+func (lim *Limiter) isClosed() bool {
+	if lim == nil {
+		return false
+	}
+
+	lim.init()
+
+	lim.mux.Lock()
+	defer lim.mux.Unlock()
+
+	return lim.closed
 }
 
 func (lim *Limiter) waitForRest() {
-	composer.GetComposer().NeedWait()
+	if lim == nil {
+		return
+	}
+
+	lim.init()
+
+	lim.mux.Lock()
+	for lim.isFull() {
+		lim.cond.Wait()
+	}
+	lim.mux.Unlock()
+}
+
+// waitForRate waits for the turn of query according to rate limit unless context is done
+func (lim *Limiter) waitForRate(ctx context.Context) error {
+	if lim == nil {
+		return nil
+	}
+
+	lim.init()
+
+	lim.mux.Lock()
+	if lim.qps <= 0 || lim.closed {
+		lim.mux.Unlock()
+
+		return nil
+	}
+
+	interval := time.Duration(float64(time.Second) / lim.qps)
+
+	slot := time.Now()
+	if lim.nextSlot.After(slot) {
+		slot = lim.nextSlot
+	}
+
+	lim.nextSlot = slot.Add(interval)
+	lim.mux.Unlock()
+
+	err := sleep(ctx, time.Until(slot))
+	if err != nil {
+		// the slot isn't used so the next queries don't wait for it
+		// unless later slots are already taken (or rate is changed) and it can't be given back
+		lim.mux.Lock()
+		if lim.nextSlot.Equal(slot.Add(interval)) {
+			lim.nextSlot = slot
+		}
+		lim.mux.Unlock()
+
+		return err
+	}
+
+	return nil
 }
@@ -0,0 +1,203 @@
+package clickhouse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// splitLiteral splits array (`[...]`), tuple (`(...)`) or map (`{...}`) literal into top level elements
+// Nested literals and quoted strings are kept as is
+func splitLiteral(value string, open, close byte) (elements []string, err error) {
+	value = strings.TrimSpace(value)
+
+	length := len(value)
+	if length < 2 || value[0] != open || value[length-1] != close {
+		return nil, fmt.Errorf("value %s isn't literal enclosed with %c%c", value, open, close)
+	}
+
+	body := value[1 : length-1]
+	if len(strings.TrimSpace(body)) == 0 {
+		return []string{}, nil
+	}
+
+	var (
+		// closers are expected closing brackets of nested literals
+		closers  []byte
+		start    int
+		isQuoted bool
+	)
+
+	for i := 0; i < len(body); i++ {
+		char := body[i]
+
+		if isQuoted {
+			switch char {
+			case '\\':
+				i++
+			case '\'':
+				isQuoted = false
+			}
+
+			continue
+		}
+
+		switch char {
+		case '\'':
+			isQuoted = true
+		case '[':
+			closers = append(closers, ']')
+		case '(':
+			closers = append(closers, ')')
+		case '{':
+			closers = append(closers, '}')
+		case ']', ')', '}':
+			if len(closers) == 0 || closers[len(closers)-1] != char {
+				return nil, fmt.Errorf("value %s has unbalanced brackets", value)
+			}
+
+			closers = closers[:len(closers)-1]
+		case ',':
+			if len(closers) == 0 {
+				elements = append(elements, strings.TrimSpace(body[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	if isQuoted || len(closers) != 0 {
+		return nil, fmt.Errorf("value %s has unbalanced brackets or quotes", value)
+	}
+
+	elements = append(elements, strings.TrimSpace(body[start:]))
+
+	return elements, nil
+}
+
+// splitPair splits `key:value` element of map literal by the top level colon
+func splitPair(element string) (key, value string, err error) {
+	var (
+		depth    int
+		isQuoted bool
+	)
+
+	for i := 0; i < len(element); i++ {
+		char := element[i]
+
+		if isQuoted {
+			switch char {
+			case '\\':
+				i++
+			case '\'':
+				isQuoted = false
+			}
+
+			continue
+		}
+
+		switch char {
+		case '\'':
+			isQuoted = true
+		case '[', '(', '{':
+			depth++
+		case ']', ')', '}':
+			depth--
+		case ':':
+			if depth == 0 {
+				return strings.TrimSpace(element[:i]), strings.TrimSpace(element[i+1:]), nil
+			}
+		}
+	}
+
+	return "", "", fmt.Errorf("element %s isn't key:value pair", element)
+}
+
+// literalText returns unquoted and unescaped content of quoted string literal, \N for NULL or the literal as is
+func literalText(literal string) string {
+	if literal == "NULL" {
+		return `\N`
+	}
+
+	length := len(literal)
+	if length < 2 || literal[0] != '\'' || literal[length-1] != '\'' {
+		return literal
+	}
+
+	return Unescape(literal[1 : length-1])
+}
+
+// ParseEnum returns mapping of names to values of Enum8 or Enum16 type e.g. Enum8('a' = 1, 'b' = 2)
+// Nullable and LowCardinality wrappers are skipped and names without values are numbered from 1 as server does
+// Quoted names may contain commas, equals signs and escaped quotes
+func ParseEnum(typeStr string) (map[string]int, error) {
+	definition := strings.TrimSpace(typeStr)
+	for _, wrapper := range []string{"Nullable(", "LowCardinality("} {
+		if strings.HasPrefix(definition, wrapper) && strings.HasSuffix(definition, ")") {
+			definition = strings.TrimSpace(definition[len(wrapper) : len(definition)-1])
+		}
+	}
+
+	index := strings.IndexByte(definition, '(')
+	if index < 0 || !strings.HasPrefix(definition, "Enum") {
+		return nil, enumError(typeStr, errors.New("it isn't Enum type"))
+	}
+
+	literals, err := splitLiteral(definition[index:], '(', ')')
+	if err != nil {
+		return nil, enumError(typeStr, err)
+	}
+
+	mapping := make(map[string]int, len(literals))
+	next := 1
+
+	for _, literal := range literals {
+		name, rest, err := splitEnumName(literal)
+		if err != nil {
+			return nil, enumError(typeStr, err)
+		}
+
+		value := next
+		if len(rest) > 0 {
+			if rest[0] != '=' {
+				return nil, enumError(typeStr, fmt.Errorf("element %s isn't 'name' = value pair", literal))
+			}
+
+			value, err = strconv.Atoi(strings.TrimSpace(rest[1:]))
+			if err != nil {
+				return nil, enumError(typeStr, fmt.Errorf("element %s has invalid value: %s", literal, err.Error()))
+			}
+		}
+
+		mapping[name] = value
+		next = value + 1
+	}
+
+	return mapping, nil
+}
+
+// splitEnumName returns unescaped quoted name of Enum element and the rest of the element after the name
+func splitEnumName(element string) (name, rest string, err error) {
+	if len(element) == 0 || element[0] != '\'' {
+		return "", "", fmt.Errorf("element %s doesn't start with quoted name", element)
+	}
+
+	for i := 1; i < len(element); i++ {
+		switch element[i] {
+		case '\\':
+			i++
+		case '\'':
+			return literalText(element[:i+1]), strings.TrimSpace(element[i+1:]), nil
+		}
+	}
+
+	return "", "", fmt.Errorf("element %s has unclosed quote", element)
+}
+
+func enumError(typeStr string, err error) error {
+	err = fmt.Errorf("can't parse enum type %s: %s", typeStr, err.Error())
+
+	cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+	return err
+}
@@ -0,0 +1,83 @@
+package clickhouse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitLiteral(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		open  byte
+		close byte
+		want  []string
+	}{
+		{"empty array", "[]", '[', ']', []string{}},
+		{"blank array", " [ ] ", '[', ']', []string{}},
+		{"numbers", "[1, 2,3]", '[', ']', []string{"1", "2", "3"}},
+		{"quoted commas", "['a,b','c']", '[', ']', []string{"'a,b'", "'c'"}},
+		{"escaped quote", `['a\',b','c']`, '[', ']', []string{`'a\',b'`, "'c'"}},
+		{"quoted brackets", "['[',']']", '[', ']', []string{"'['", "']'"}},
+		{"nested", "[[1,2],(3,4),{'k':5}]", '[', ']', []string{"[1,2]", "(3,4)", "{'k':5}"}},
+		{"tuple", "('a', 1, NULL)", '(', ')', []string{"'a'", "1", "NULL"}},
+		{"map", "{'a':1,'b':[2,3]}", '{', '}', []string{"'a':1", "'b':[2,3]"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := splitLiteral(test.value, test.open, test.close)
+			if err != nil {
+				t.Fatalf("splitLiteral(%q) returns error %s", test.value, err.Error())
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("splitLiteral(%q) = %q; want %q", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitLiteralErrors(t *testing.T) {
+	for _, value := range []string{"", "[", "1,2", "(1,2]", "[[1,2]", "[1]]", "['a]", "[(1,2]]"} {
+		if _, err := splitLiteral(value, '[', ']'); err == nil {
+			t.Errorf("splitLiteral(%q) doesn't return error", value)
+		}
+	}
+}
+
+func TestParseEnum(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  string
+		want map[string]int
+	}{
+		{"enum8", "Enum8('a' = 1, 'b' = 2)", map[string]int{"a": 1, "b": 2}},
+		{"enum16 negative", "Enum16('neg' = -1000, 'pos' = 1000)", map[string]int{"neg": -1000, "pos": 1000}},
+		{"implicit values", "Enum('a', 'b' = 5, 'c')", map[string]int{"a": 1, "b": 5, "c": 6}},
+		{"escaped names", `Enum8('it\'s' = 1, 'a,b' = 2, 'x = y' = 3)`, map[string]int{"it's": 1, "a,b": 2, "x = y": 3}},
+		{"nullable", "Nullable(Enum8('a' = 1))", map[string]int{"a": 1}},
+		{"low cardinality", "LowCardinality(Enum8('a' = 1))", map[string]int{"a": 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseEnum(test.typ)
+			if err != nil {
+				t.Fatalf("ParseEnum(%q) returns error %s", test.typ, err.Error())
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseEnum(%q) = %v; want %v", test.typ, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseEnumErrors(t *testing.T) {
+	for _, typ := range []string{"String", "Enum8", "Enum8(a = 1)", "Enum8('a' 1)", "Enum8('a' = x)", "Enum8('a' = 1"} {
+		if _, err := ParseEnum(typ); err == nil {
+			t.Errorf("ParseEnum(%q) doesn't return error", typ)
+		}
+	}
+}
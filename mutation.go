@@ -0,0 +1,181 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// mutationPollInterval is interval of polling of system.mutations by WaitMutation
+const mutationPollInterval = 500 * time.Millisecond
+
+// MutationError is returned by WaitMutation if server can't apply the mutation
+type MutationError struct {
+	Database   string
+	Table      string
+	MutationID string
+	// Reason is the latest fail reason of the mutation from system.mutations
+	Reason string
+}
+
+// Error returns reason of failed mutation
+func (err *MutationError) Error() string {
+	table := err.Table
+	if len(err.Database) > 0 {
+		table = err.Database + "." + table
+	}
+
+	return fmt.Sprintf("mutation %s of %s failed: %s", err.MutationID, table, err.Reason)
+}
+
+var mutationRe = regexp.MustCompile("(?is)^\\s*ALTER\\s+TABLE\\s+(?:(`[^`]+`|[A-Za-z0-9_]+)\\.)?(`[^`]+`|[A-Za-z0-9_]+)\\s.*\\b(UPDATE|DELETE)\\b")
+
+// Mutate executes ALTER TABLE ... UPDATE or DELETE query and returns ID of the mutation created by it
+// The mutation is applied asynchronously so WaitMutation has to be used to wait till it's done
+func (conn *Conn) Mutate(ctx context.Context, query string) (string, error) {
+	matches := mutationRe.FindStringSubmatch(query)
+	if matches == nil {
+		err := errors.New("query isn't ALTER TABLE ... UPDATE or DELETE mutation")
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return "", err
+	}
+
+	database, table := unquoteIdent(matches[1]), unquoteIdent(matches[2])
+
+	// server doesn't return ID of mutation so it's found as the new one of the table
+	before, err := conn.mutationIDs(database, table)
+	if err != nil {
+		return "", err
+	}
+
+	known := make(map[string]bool, len(before))
+	for _, id := range before {
+		known[id] = true
+	}
+
+	conn.waitForRest()
+	conn.increase()
+
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	_, err = conn.exec(ctx, query, nil)
+
+	conn.reduce()
+
+	if err != nil {
+		return "", err
+	}
+
+	ids, err := conn.mutationIDs(database, table)
+	if err != nil {
+		return "", err
+	}
+
+	for index := len(ids) - 1; index >= 0; index-- {
+		if !known[ids[index]] {
+			message = fmt.Sprintf("Mutation %s is created", ids[index])
+			cfg.logger.debug(message)
+
+			return ids[index], nil
+		}
+	}
+
+	err = fmt.Errorf("mutation of table %s isn't found in system.mutations", table)
+
+	message = fmt.Sprintf("Catch error %s", err.Error())
+	cfg.logger.error(message)
+
+	return "", err
+}
+
+// mutationIDs returns IDs of mutations of table in order of creation
+func (conn *Conn) mutationIDs(database, table string) (ids []string, err error) {
+	query := fmt.Sprintf("SELECT mutation_id FROM system.mutations WHERE database = %s AND table = '%s' "+
+		"ORDER BY create_time, mutation_id FORMAT TabSeparatedWithNames", mutationDatabase(database), escapeValuesString(table))
+
+	err = conn.eachRow(query, func(result Result) (err error) {
+		id, err := result.String("mutation_id")
+		ids = append(ids, id)
+
+		return
+	})
+
+	return ids, err
+}
+
+// WaitMutation polls system.mutations till the mutation of `database.table` table is done (empty database is the current one)
+// It returns *MutationError with the latest fail reason if server fails to apply the mutation
+// and error of context if it's done before the mutation
+func (conn *Conn) WaitMutation(ctx context.Context, database, table, mutationID string) error {
+	query := fmt.Sprintf("SELECT is_done, latest_fail_reason FROM system.mutations "+
+		"WHERE database = %s AND table = '%s' AND mutation_id = '%s' FORMAT TabSeparatedWithNames",
+		mutationDatabase(database), escapeValuesString(table), escapeValuesString(mutationID))
+
+	for {
+		var (
+			found  bool
+			done   bool
+			reason string
+		)
+
+		err := conn.eachRow(query, func(result Result) (err error) {
+			found = true
+
+			if done, err = result.Bool("is_done"); err != nil {
+				return
+			}
+
+			reason, err = result.String("latest_fail_reason")
+
+			return
+		})
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			err = fmt.Errorf("mutation %s of table %s isn't found in system.mutations (it may be killed)", mutationID, table)
+
+			message := fmt.Sprintf("Catch error %s", err.Error())
+			cfg.logger.error(message)
+
+			return err
+		}
+
+		if done {
+			message := fmt.Sprintf("Mutation %s is done", mutationID)
+			cfg.logger.debug(message)
+
+			return nil
+		}
+
+		if len(reason) > 0 {
+			err = &MutationError{Database: database, Table: table, MutationID: mutationID, Reason: reason}
+
+			message := fmt.Sprintf("Catch error %s", err.Error())
+			cfg.logger.error(message)
+
+			return err
+		}
+
+		err = sleep(ctx, mutationPollInterval)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// mutationDatabase returns SQL literal of database or the current database if it's empty
+func mutationDatabase(database string) string {
+	if len(database) == 0 {
+		return "currentDatabase()"
+	}
+
+	return "'" + escapeValuesString(database) + "'"
+}
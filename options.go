@@ -0,0 +1,101 @@
+package clickhouse
+
+// Option configures connection created by NewWithOptions
+type Option func(conn *Conn)
+
+// NewWithOptions creates connection configured by options
+// Options are applied before the connection is returned so they are safe to use across goroutines
+func NewWithOptions(host string, port int, opts ...Option) *Conn {
+	conn := New(host, port, "", "")
+
+	for _, opt := range opts {
+		opt(conn)
+	}
+
+	return conn
+}
+
+// WithCredentials sets user and password
+func WithCredentials(user, pass string) Option {
+	return func(conn *Conn) {
+		conn.user = user
+		conn.pass = pass
+	}
+}
+
+// WithProtocol sets protocol
+func WithProtocol(protocol string) Option {
+	return func(conn *Conn) {
+		conn.Protocol(protocol)
+	}
+}
+
+// WithDefaultDatabase sets default database for queries
+func WithDefaultDatabase(name string) Option {
+	return func(conn *Conn) {
+		conn.Database(name)
+	}
+}
+
+// WithHeader sets HTTP header sent with every query
+func WithHeader(name, value string) Option {
+	return func(conn *Conn) {
+		conn.Header(name, value)
+	}
+}
+
+// WithMaxMemoryUsage sets maximum memory usage per query
+func WithMaxMemoryUsage(limit int) Option {
+	return func(conn *Conn) {
+		conn.MaxMemoryUsage(limit)
+	}
+}
+
+// WithConnectTimeout sets connection timeout
+func WithConnectTimeout(timeout int) Option {
+	return func(conn *Conn) {
+		conn.ConnectTimeout(timeout)
+	}
+}
+
+// WithSendTimeout sets send timeout
+func WithSendTimeout(timeout int) Option {
+	return func(conn *Conn) {
+		conn.SendTimeout(timeout)
+	}
+}
+
+// WithReceiveTimeout sets receive timeout
+func WithReceiveTimeout(timeout int) Option {
+	return func(conn *Conn) {
+		conn.ReceiveTimeout(timeout)
+	}
+}
+
+// WithCompression sets response compression
+func WithCompression(compression bool) Option {
+	return func(conn *Conn) {
+		conn.Compression(compression)
+	}
+}
+
+// WithAttempts sets amount of attempts and wait between them
+func WithAttempts(amount int, wait int) Option {
+	return func(conn *Conn) {
+		conn.Attempts(amount, wait)
+	}
+}
+
+// WithMaxRequests sets requests limitation
+func WithMaxRequests(limit int) Option {
+	return func(conn *Conn) {
+		conn.MaxRequests(limit)
+	}
+}
+
+// WithConnectRetries sets amount of retries to establish connection and wait between them in milliseconds
+func WithConnectRetries(amount int, wait int) Option {
+	return func(conn *Conn) {
+		conn.ConnectRetries(amount, wait)
+	}
+}
@@ -0,0 +1,158 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// failTimeout is time while host which failed connecting isn't handed out by pool
+const failTimeout = 10 * time.Second
+
+// Pool hands out connections to several hosts in round-robin order and limits amount of simultaneously used connections
+// Hosts which failed connecting are skipped for some time unless all hosts failed
+type Pool struct {
+	conns  []*Conn
+	slots  chan struct{}
+	next   uint32
+	failed []int64
+}
+
+// NewPool creates pool of connections (usually one per host) which allows to use up to maxConns of them simultaneously
+// Zero or negative maxConns means there is no limitation
+func NewPool(maxConns int, conns ...*Conn) *Pool {
+	pool := &Pool{
+		conns:  conns,
+		failed: make([]int64, len(conns))}
+
+	if maxConns > 0 {
+		pool.slots = make(chan struct{}, maxConns)
+	}
+
+	message := fmt.Sprintf("Pool is initialized with %d connections (max simultaneously used = %d)", len(conns), maxConns)
+	cfg.logger.info(message)
+
+	return pool
+}
+
+// Get waits for free slot and returns connection of the next available host
+// The connection has to be returned with Put after usage
+func (pool *Pool) Get() (*Conn, error) {
+	return pool.GetContext(context.Background())
+}
+
+// GetContext is like Get but it stops waiting for free slot when context is done
+func (pool *Pool) GetContext(ctx context.Context) (*Conn, error) {
+	if len(pool.conns) == 0 {
+		return nil, errors.New("pool has no connections")
+	}
+
+	if pool.slots != nil {
+		select {
+		case pool.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return pool.conns[pool.pick()], nil
+}
+
+// Put releases slot of connection taken with Get
+func (pool *Pool) Put(conn *Conn) {
+	if pool.slots == nil {
+		return
+	}
+
+	select {
+	case <-pool.slots:
+	default:
+		cfg.logger.warn("Catch warning connection is put into pool without getting")
+	}
+}
+
+// Exec executes query with connection of the next available host
+// The query is sent to another host only if connecting failed so it's safe for non-idempotent queries
+func (pool *Pool) Exec(query string) error {
+	return pool.do(func(conn *Conn) error {
+		return conn.Exec(query)
+	})
+}
+
+// FetchAll executes query with connection of the next available host and returns all rows
+func (pool *Pool) FetchAll(query string) (results []Result, skipped int, err error) {
+	err = pool.do(func(conn *Conn) (err error) {
+		results, skipped, err = conn.FetchAll(query)
+
+		return
+	})
+
+	return
+}
+
+// Close closes all connections of the pool
+func (pool *Pool) Close() error {
+	for _, conn := range pool.conns {
+		conn.Close()
+	}
+
+	return nil
+}
+
+func (pool *Pool) do(fn func(conn *Conn) error) (err error) {
+	for attempt := 0; attempt < len(pool.conns) || attempt == 0; attempt++ {
+		var conn *Conn
+		conn, err = pool.Get()
+		if err != nil {
+			return
+		}
+
+		err = fn(conn)
+		pool.Put(conn)
+
+		if !isConnectError(err) {
+			return
+		}
+
+		pool.fail(conn)
+
+		message := fmt.Sprintf("Catch warning host %s:%d is failed: %s", conn.host, conn.port, err.Error())
+		cfg.logger.warn(message)
+	}
+
+	return
+}
+
+// pick returns index of the next host which didn't fail recently or just the next one if all hosts failed
+func (pool *Pool) pick() int {
+	amount := uint32(len(pool.conns))
+	start := atomic.AddUint32(&pool.next, 1)
+	now := time.Now().UnixNano()
+
+	for i := uint32(0); i < amount; i++ {
+		index := (start + i) % amount
+		if atomic.LoadInt64(&pool.failed[index]) <= now {
+			return int(index)
+		}
+	}
+
+	return int(start % amount)
+}
+
+func (pool *Pool) fail(conn *Conn) {
+	for index, c := range pool.conns {
+		if c == conn {
+			atomic.StoreInt64(&pool.failed[index], time.Now().Add(failTimeout).UnixNano())
+		}
+	}
+}
+
+// isConnectError checks if error happened on connecting or circuit breaker is open so the query wasn't sent
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+
+	return errors.As(err, &opErr) && opErr.Op == "dial" || errors.Is(err, ErrCircuitOpen)
+}
@@ -0,0 +1,87 @@
+package clickhouse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Progress describes progress of query reported by server in X-ClickHouse-Progress headers
+type Progress struct {
+	ReadRows  uint64
+	ReadBytes uint64
+	TotalRows uint64
+	Elapsed   time.Duration
+}
+
+// Percent returns percentage of read rows or zero if total amount of rows is unknown
+func (progress Progress) Percent() float64 {
+	if progress.TotalRows == 0 {
+		return 0
+	}
+
+	return float64(progress.ReadRows) / float64(progress.TotalRows) * 100
+}
+
+// ETA returns rough estimation of time left till all rows are read or zero if it can't be estimated
+func (progress Progress) ETA() time.Duration {
+	if progress.ReadRows == 0 || progress.TotalRows <= progress.ReadRows {
+		return 0
+	}
+
+	left := float64(progress.TotalRows-progress.ReadRows) / float64(progress.ReadRows)
+
+	return time.Duration(float64(progress.Elapsed) * left)
+}
+
+type progressHeader struct {
+	ReadRows  uint64 `json:"read_rows,string"`
+	ReadBytes uint64 `json:"read_bytes,string"`
+	TotalRows uint64 `json:"total_rows_to_read,string"`
+	ElapsedNs uint64 `json:"elapsed_ns,string"`
+}
+
+type progressKey struct{}
+
+// WithProgress returns copy of context with callback which receives progress of queries executed with the context
+// It turns on send_progress_in_http_headers so server sends response status before the query is finished
+// HTTP client gets headers only when all of them are received so the callback is called when the response starts
+func WithProgress(ctx context.Context, fn func(progress Progress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func getProgress(ctx context.Context) func(progress Progress) {
+	fn, _ := ctx.Value(progressKey{}).(func(progress Progress))
+
+	return fn
+}
+
+// reportProgress calls callback for every progress header of response in order of the headers
+func reportProgress(header http.Header, start time.Time, fn func(progress Progress)) {
+	for _, value := range header.Values("X-ClickHouse-Progress") {
+		var parsed progressHeader
+
+		err := json.Unmarshal([]byte(value), &parsed)
+		if err != nil {
+			message := fmt.Sprintf("Catch warning can't parse progress %s: %s", value, err.Error())
+			cfg.logger.warn(message)
+
+			continue
+		}
+
+		progress := Progress{
+			ReadRows:  parsed.ReadRows,
+			ReadBytes: parsed.ReadBytes,
+			TotalRows: parsed.TotalRows,
+			Elapsed:   time.Duration(parsed.ElapsedNs)}
+
+		// old servers don't report elapsed time
+		if progress.Elapsed == 0 {
+			progress.Elapsed = time.Since(start)
+		}
+
+		fn(progress)
+	}
+}
@@ -0,0 +1,45 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+type rawResponseKey struct{}
+
+// withRawResponse returns copy of context which makes query return response as is into passed slot
+func withRawResponse(ctx context.Context, slot **http.Response) context.Context {
+	return context.WithValue(ctx, rawResponseKey{}, slot)
+}
+
+func getRawResponse(ctx context.Context) **http.Response {
+	slot, _ := ctx.Value(rawResponseKey{}).(**http.Response)
+
+	return slot
+}
+
+// Do executes query with settings and credentials of connection and returns response of server as is
+// The query isn't changed (e.g. FORMAT isn't set), status isn't checked and body isn't decoded
+// (it's gzip compressed if Compression is on) so headers, status and trailers can be handled by caller
+// Only failed connections are retried. The caller owns the body and has to close it
+func (conn *Conn) Do(ctx context.Context, query string) (*http.Response, error) {
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	var res *http.Response
+
+	reader, _, err := conn.doQuery(withRawResponse(ctx, &res), query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// closing of body releases context of the query
+	res.Body = reader
+
+	return res, nil
+}
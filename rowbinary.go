@@ -0,0 +1,287 @@
+package clickhouse
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RowBinaryEncoder encodes Go values into RowBinary rows of fixed column types to insert with InsertBatch and RowBinary format
+// Unlike RowWriter values aren't escaped as text so it's faster for large inserts but types of all columns have to be known
+type RowBinaryEncoder struct {
+	w      io.Writer
+	types  []binaryType
+	buffer []byte
+}
+
+// binaryType is parsed column type of RowBinaryEncoder
+type binaryType struct {
+	name     string
+	size     int
+	nullable bool
+}
+
+var fixedStringRe = regexp.MustCompile(`^FixedString\((\d+)\)$`)
+
+// NewRowBinaryEncoder creates encoder of rows with columns of passed types over passed writer
+// Supported types are Int8-Int64, UInt8-UInt64, Float32, Float64, Bool, String, FixedString(N),
+// Date, Date32 and DateTime (with or without time zone) wrapped with Nullable or LowCardinality
+func NewRowBinaryEncoder(w io.Writer, types []string) (*RowBinaryEncoder, error) {
+	parsed := make([]binaryType, 0, len(types))
+	for index, typ := range types {
+		binType, err := parseBinaryType(typ)
+		if err != nil {
+			err = fmt.Errorf("can't encode column %d: %s", index, err.Error())
+
+			message := fmt.Sprintf("Catch error %s", err.Error())
+			cfg.logger.error(message)
+
+			return nil, err
+		}
+
+		parsed = append(parsed, binType)
+	}
+
+	return &RowBinaryEncoder{
+		w:     w,
+		types: parsed}, nil
+}
+
+func parseBinaryType(typ string) (binaryType, error) {
+	var binType binaryType
+
+	name := strings.TrimSpace(typ)
+	for _, wrapper := range []string{"LowCardinality(", "Nullable("} {
+		if strings.HasPrefix(name, wrapper) && strings.HasSuffix(name, ")") {
+			binType.nullable = binType.nullable || wrapper == "Nullable("
+			name = strings.TrimSpace(name[len(wrapper) : len(name)-1])
+		}
+	}
+
+	if matches := fixedStringRe.FindStringSubmatch(name); len(matches) > 1 {
+		size, err := strconv.Atoi(matches[1])
+		if err != nil || size == 0 {
+			return binaryType{}, fmt.Errorf("type `%s` has invalid size", typ)
+		}
+
+		binType.name, binType.size = "FixedString", size
+
+		return binType, nil
+	}
+
+	if strings.HasPrefix(name, "DateTime(") && strings.HasSuffix(name, ")") {
+		// time zone affects only text representation of DateTime
+		name = "DateTime"
+	}
+
+	switch name {
+	case "Int8", "UInt8", "Bool":
+		binType.size = 1
+	case "Int16", "UInt16", "Date":
+		binType.size = 2
+	case "Int32", "UInt32", "Float32", "Date32", "DateTime":
+		binType.size = 4
+	case "Int64", "UInt64", "Float64":
+		binType.size = 8
+	case "String":
+	default:
+		return binaryType{}, fmt.Errorf("type `%s` isn't supported", typ)
+	}
+
+	binType.name = name
+
+	return binType, nil
+}
+
+// WriteRow encodes values in order of column types and writes them as one RowBinary row
+// Nil pointers are written as NULL of Nullable columns and other pointers are written as values they point to
+func (enc *RowBinaryEncoder) WriteRow(values ...interface{}) error {
+	if len(values) != len(enc.types) {
+		return fmt.Errorf("row has %d values but there are %d column types", len(values), len(enc.types))
+	}
+
+	enc.buffer = enc.buffer[:0]
+
+	var err error
+	for index, value := range values {
+		enc.buffer, err = appendBinary(enc.buffer, enc.types[index], indirect(value))
+		if err != nil {
+			return fmt.Errorf("can't encode field %d: %s", index, err.Error())
+		}
+	}
+
+	_, err = enc.w.Write(enc.buffer)
+
+	return err
+}
+
+func appendBinary(buffer []byte, typ binaryType, value interface{}) ([]byte, error) {
+	if typ.nullable {
+		if value == nil {
+			return append(buffer, 1), nil
+		}
+
+		buffer = append(buffer, 0)
+	} else if value == nil {
+		return nil, fmt.Errorf("can't write NULL as %s", typ.name)
+	}
+
+	switch typ.name {
+	case "String":
+		bs, ok := binaryBytes(value)
+		if !ok {
+			return nil, fmt.Errorf("can't write %T as String", value)
+		}
+
+		var length [binary.MaxVarintLen64]byte
+		buffer = append(buffer, length[:binary.PutUvarint(length[:], uint64(len(bs)))]...)
+
+		return append(buffer, bs...), nil
+	case "FixedString":
+		bs, ok := binaryBytes(value)
+		if !ok {
+			return nil, fmt.Errorf("can't write %T as FixedString(%d)", value, typ.size)
+		}
+
+		if len(bs) > typ.size {
+			return nil, fmt.Errorf("value of %d bytes is longer than FixedString(%d)", len(bs), typ.size)
+		}
+
+		buffer = append(buffer, bs...)
+
+		// shorter values are padded with zero bytes as server does
+		return append(buffer, make([]byte, typ.size-len(bs))...), nil
+	case "Bool":
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("can't write %T as Bool", value)
+		}
+
+		if flag {
+			return append(buffer, 1), nil
+		}
+
+		return append(buffer, 0), nil
+	case "Float32", "Float64":
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Float32 && rv.Kind() != reflect.Float64 {
+			return nil, fmt.Errorf("can't write %T as %s", value, typ.name)
+		}
+
+		if typ.size == 4 {
+			return appendLittleEndian(buffer, uint64(math.Float32bits(float32(rv.Float()))), 4), nil
+		}
+
+		return appendLittleEndian(buffer, math.Float64bits(rv.Float()), 8), nil
+	case "Date", "Date32", "DateTime":
+		t, ok := value.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("can't write %T as %s", value, typ.name)
+		}
+
+		return appendBinaryTime(buffer, typ.name, t)
+	}
+
+	return appendBinaryInt(buffer, typ, value)
+}
+
+// binaryBytes returns bytes of string value
+func binaryBytes(value interface{}) ([]byte, bool) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), true
+	case []byte:
+		return v, true
+	}
+
+	return nil, false
+}
+
+// appendBinaryTime appends date of time (in its location) as amount of days or time as Unix timestamp
+func appendBinaryTime(buffer []byte, typ string, t time.Time) ([]byte, error) {
+	if typ == "DateTime" {
+		if t.Unix() < 0 || t.Unix() > math.MaxUint32 {
+			return nil, fmt.Errorf("time %s is out of range of DateTime", t.Format("2006-01-02 15:04:05"))
+		}
+
+		return appendLittleEndian(buffer, uint64(t.Unix()), 4), nil
+	}
+
+	year, month, day := t.Date()
+	days := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+
+	if typ == "Date32" {
+		if err := checkDate32(t); err != nil {
+			return nil, err
+		}
+
+		return appendLittleEndian(buffer, uint64(days), 4), nil
+	}
+
+	if days < 0 || days > math.MaxUint16 {
+		return nil, fmt.Errorf("date %s is out of range of Date", t.Format("2006-01-02"))
+	}
+
+	return appendLittleEndian(buffer, uint64(days), 2), nil
+}
+
+// appendBinaryInt appends integer value of any Go integer type as little-endian integer of column size
+func appendBinaryInt(buffer []byte, typ binaryType, value interface{}) ([]byte, error) {
+	bits := typ.size * 8
+
+	var number uint64
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		signed := rv.Int()
+
+		var fits bool
+		if strings.HasPrefix(typ.name, "U") {
+			fits = signed >= 0 && (bits == 64 || signed < 1<<bits)
+		} else {
+			fits = bits == 64 || (signed >= -1<<(bits-1) && signed < 1<<(bits-1))
+		}
+
+		if !fits {
+			return nil, fmt.Errorf("value %d is out of range of %s", signed, typ.name)
+		}
+
+		number = uint64(signed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		unsigned := rv.Uint()
+
+		limit := uint64(math.MaxUint64)
+		if strings.HasPrefix(typ.name, "U") && bits < 64 {
+			limit = 1<<bits - 1
+		} else if !strings.HasPrefix(typ.name, "U") {
+			limit = 1<<(bits-1) - 1
+		}
+
+		if unsigned > limit {
+			return nil, fmt.Errorf("value %d is out of range of %s", unsigned, typ.name)
+		}
+
+		number = unsigned
+	default:
+		return nil, fmt.Errorf("can't write %T as %s", value, typ.name)
+	}
+
+	return appendLittleEndian(buffer, number, typ.size), nil
+}
+
+// appendLittleEndian appends size lower bytes of number in little-endian order
+// (negative numbers are passed in two's complement so their lower bytes are the same)
+func appendLittleEndian(buffer []byte, number uint64, size int) []byte {
+	for i := 0; i < size; i++ {
+		buffer = append(buffer, byte(number>>(8*i)))
+	}
+
+	return buffer
+}
@@ -0,0 +1,225 @@
+package clickhouse
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Scan copies columns of the current row into values pointed at by dest in order of the query columns
+// Supported destinations are pointers to string, []byte, bool, integers, floats, time.Time, *time.Time (nil for NULL), time.Duration (seconds),
+// interface{} (raw string or nil for NULL) and implementations of sql.Scanner
+func (iter *Iter) Scan(dest ...interface{}) error {
+	columns := iter.ColumnNames()
+	if len(dest) != len(columns) {
+		err := fmt.Errorf("expected %d destination arguments in scan, not %d", len(columns), len(dest))
+
+		cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+		return err
+	}
+
+	for index, column := range columns {
+		err := iter.Result.scanInto(column, dest[index])
+		if err != nil {
+			err = fmt.Errorf("can't scan column %d `%s`: %s", index, column, err.Error())
+
+			cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ScanStruct copies columns into exported fields of struct pointed at by dest
+// Fields are matched with columns by `ch:"column"` tag or by name, fields tagged with `ch:"-"` and fields without column are skipped
+// Supported field types are the same as of Iter.Scan including implementations of sql.Scanner (e.g. sql.NullString)
+func (result Result) ScanStruct(dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		err := fmt.Errorf("can't scan into %T (pointer to struct is expected)", dest)
+
+		cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+		return err
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+
+		column := field.Tag.Get("ch")
+		if len(field.PkgPath) > 0 || column == "-" {
+			continue
+		}
+
+		if len(column) == 0 {
+			column = field.Name
+		}
+
+		if _, ok := result.value(column); !ok {
+			continue
+		}
+
+		err := result.scanInto(column, rv.Field(i).Addr().Interface())
+		if err != nil {
+			err = fmt.Errorf("can't scan column `%s` into field %s: %s", column, field.Name, err.Error())
+
+			cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (result Result) scanInto(column string, dest interface{}) (err error) {
+	switch d := dest.(type) {
+	case *string:
+		*d, err = result.String(column)
+	case *[]byte:
+		*d, err = result.Bytes(column)
+	case *bool:
+		*d, err = result.Bool(column)
+	case *uint8, *uint16, *uint32, *uint64, *uint, *int8, *int16, *int32, *int64, *int:
+		err = result.scanInteger(column, d)
+	case *float32:
+		*d, err = result.Float32(column)
+	case *float64:
+		*d, err = result.Float64(column)
+	case *time.Duration:
+		*d, err = result.Duration(column, time.Second)
+	case *time.Time:
+		var value string
+		value, err = result.String(column)
+		if err != nil {
+			return
+		}
+
+		if len(value) == len("2006-01-02") {
+			*d, err = result.Date(column)
+		} else {
+			*d, err = result.DateTime(column)
+		}
+	case **time.Time:
+		var value string
+		value, err = result.String(column)
+		if err != nil {
+			return
+		}
+
+		if len(value) == len("2006-01-02") {
+			*d, err = result.NullableDate(column)
+		} else {
+			*d, err = result.NullableDateTime(column)
+		}
+	case *interface{}:
+		var value string
+		value, err = result.String(column)
+		if err != nil {
+			return
+		}
+
+		if value == `\N` {
+			*d = nil
+		} else {
+			*d = value
+		}
+	case sql.Scanner:
+		var value string
+		value, err = result.String(column)
+		if err != nil {
+			return
+		}
+
+		if value == `\N` {
+			err = d.Scan(nil)
+		} else {
+			err = d.Scan(value)
+		}
+	default:
+		err = fmt.Errorf("unsupported destination type %T", dest)
+	}
+
+	return
+}
+
+func (result Result) scanInteger(column string, dest interface{}) (err error) {
+	switch d := dest.(type) {
+	case *uint8:
+		*d, err = result.UInt8(column)
+	case *uint16:
+		*d, err = result.UInt16(column)
+	case *uint32:
+		*d, err = result.UInt32(column)
+	case *uint64:
+		*d, err = result.UInt64(column)
+	case *uint:
+		var ui64 uint64
+		ui64, err = result.getUInt(column, strconv.IntSize)
+		*d = uint(ui64)
+	case *int8:
+		*d, err = result.Int8(column)
+	case *int16:
+		*d, err = result.Int16(column)
+	case *int32:
+		*d, err = result.Int32(column)
+	case *int64:
+		*d, err = result.Int64(column)
+	case *int:
+		var i64 int64
+		i64, err = result.getInt(column, strconv.IntSize)
+		*d = int(i64)
+	}
+
+	if err == nil || result.conn == nil || !result.conn.isLenientScan() {
+		return
+	}
+
+	return result.truncateInteger(column, dest, err)
+}
+
+// truncateInteger sets float value into integer destination with warning instead of failing with err
+// Values which don't fit the destination (and NULL) still fail with err
+func (result Result) truncateInteger(column string, dest interface{}, err error) error {
+	value, strErr := result.String(column)
+	if strErr != nil {
+		return err
+	}
+
+	f, parseErr := strconv.ParseFloat(value, 64)
+	if parseErr != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return err
+	}
+
+	truncated := math.Trunc(f)
+	rv := reflect.ValueOf(dest).Elem()
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if truncated < math.MinInt64 || truncated >= math.MaxInt64 || rv.OverflowInt(int64(truncated)) {
+			return err
+		}
+
+		rv.SetInt(int64(truncated))
+	default:
+		if truncated < 0 || truncated >= math.MaxUint64 || rv.OverflowUint(uint64(truncated)) {
+			return err
+		}
+
+		rv.SetUint(uint64(truncated))
+	}
+
+	message := fmt.Sprintf("Catch warning value %s of column `%s` is truncated to %s", value, column, strconv.FormatFloat(truncated, 'f', -1, 64))
+	cfg.logger.warn(message)
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package clickhouse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ExecResult contains progress summary of executed query reported by server in X-ClickHouse-Summary header
+// The summary is complete only if the response is sent after the query is finished (see WaitEndOfQuery)
+type ExecResult struct {
+	ReadRows     uint64 `json:"read_rows,string"`
+	ReadBytes    uint64 `json:"read_bytes,string"`
+	WrittenRows  uint64 `json:"written_rows,string"`
+	WrittenBytes uint64 `json:"written_bytes,string"`
+	// TotalRowsToRead is amount of rows the server estimates to read for the query or zero if it's unknown
+	TotalRowsToRead uint64 `json:"total_rows_to_read,string"`
+	// QueryID is ID of the query returned by server in X-ClickHouse-Query-Id header
+	QueryID string `json:"-"`
+}
+
+// ExecWithResult executes new query and returns its summary
+func (conn *Conn) ExecWithResult(query string) (ExecResult, error) {
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	return conn.exec(context.Background(), query, nil)
+}
+
+// InsertSelect inserts result of select query (e.g. from remote() or url() table functions) into `database.table` table
+// and returns summary with amount of written rows
+// Columns of the table are set in order of the select query columns (all columns of the table if there are no columns)
+func (conn *Conn) InsertSelect(database, table string, columns []string, selectQuery string) (ExecResult, error) {
+	selectQuery = strings.TrimRight(strings.TrimSpace(selectQuery), "; \t\r\n")
+	if len(selectQuery) == 0 {
+		err := errors.New("can't insert result of empty select query")
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return ExecResult{}, err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s.%s %s", database, table, selectQuery)
+	if len(columns) > 0 {
+		query = fmt.Sprintf("INSERT INTO %s.%s (%s) %s", database, table, strings.Join(conn.quoteColumns(columns), ", "), selectQuery)
+	}
+
+	conn.waitForRest()
+	conn.increase()
+	defer conn.reduce()
+
+	message := fmt.Sprintf("Try to execute: %s", cutOffQuery(query, 500))
+	cfg.logger.debug(message)
+
+	// the summary is complete only after the end of the query
+	ctx := WithSetting(context.Background(), "wait_end_of_query", "1")
+
+	return conn.exec(ctx, query, nil)
+}
+
+func parseSummary(header http.Header) (result ExecResult) {
+	summary := header.Get("X-ClickHouse-Summary")
+	if len(summary) == 0 {
+		return
+	}
+
+	err := json.Unmarshal([]byte(summary), &result)
+	if err != nil {
+		// the query is executed already so broken summary isn't a reason to fail it
+		message := fmt.Sprintf("Catch warning can't parse summary %s: %s", summary, err.Error())
+		cfg.logger.warn(message)
+
+		return ExecResult{}
+	}
+
+	return
+}
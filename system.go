@@ -0,0 +1,325 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnInfo describes column of table from system.columns
+type ColumnInfo struct {
+	Name              string
+	Type              string
+	Position          uint64
+	DefaultKind       string
+	DefaultExpression string
+	Comment           string
+	IsInPrimaryKey    bool
+	IsInSortingKey    bool
+}
+
+// ColumnDetail describes column of table with compression details from system.columns
+type ColumnDetail struct {
+	Name              string
+	Type              string
+	DefaultKind       string
+	DefaultExpression string
+	Codec             string
+	Comment           string
+	CompressedBytes   uint64
+	UncompressedBytes uint64
+}
+
+// PartInfo describes data part of table from system.parts
+type PartInfo struct {
+	Name             string
+	Partition        string
+	Active           bool
+	Rows             uint64
+	BytesOnDisk      uint64
+	ModificationTime time.Time
+}
+
+// InsertedPart describes data part created by insert from system.part_log
+type InsertedPart struct {
+	Database    string
+	Table       string
+	Name        string
+	PartitionID string
+	MinBlock    uint64
+	MaxBlock    uint64
+	Rows        uint64
+	Bytes       uint64
+}
+
+// Tables returns names of tables of database
+func (conn *Conn) Tables(database string) (tables []string, err error) {
+	query := fmt.Sprintf("SELECT name FROM system.tables WHERE database = '%s' ORDER BY name FORMAT TabSeparatedWithNames",
+		escapeValuesString(database))
+
+	err = conn.eachRow(query, func(result Result) (err error) {
+		name, err := result.String("name")
+		tables = append(tables, name)
+
+		return
+	})
+
+	return tables, err
+}
+
+// Columns returns columns of table in order of the table
+func (conn *Conn) Columns(database, table string) (columns []ColumnInfo, err error) {
+	query := fmt.Sprintf("SELECT name, type, position, default_kind, default_expression, comment, is_in_primary_key, is_in_sorting_key "+
+		"FROM system.columns WHERE database = '%s' AND table = '%s' ORDER BY position FORMAT TabSeparatedWithNames",
+		escapeValuesString(database), escapeValuesString(table))
+
+	err = conn.eachRow(query, func(result Result) (err error) {
+		var column ColumnInfo
+
+		if column.Name, err = result.String("name"); err != nil {
+			return
+		}
+
+		if column.Type, err = result.String("type"); err != nil {
+			return
+		}
+
+		if column.Position, err = result.UInt64("position"); err != nil {
+			return
+		}
+
+		if column.DefaultKind, err = result.String("default_kind"); err != nil {
+			return
+		}
+
+		if column.DefaultExpression, err = result.String("default_expression"); err != nil {
+			return
+		}
+
+		if column.Comment, err = result.String("comment"); err != nil {
+			return
+		}
+
+		if column.IsInPrimaryKey, err = result.Bool("is_in_primary_key"); err != nil {
+			return
+		}
+
+		if column.IsInSortingKey, err = result.Bool("is_in_sorting_key"); err != nil {
+			return
+		}
+
+		columns = append(columns, column)
+
+		return
+	})
+
+	return columns, err
+}
+
+// ColumnDetails returns columns of table with compression codecs and sizes in order of the table
+func (conn *Conn) ColumnDetails(database, table string) (columns []ColumnDetail, err error) {
+	query := fmt.Sprintf("SELECT name, type, default_kind, default_expression, compression_codec, comment, "+
+		"data_compressed_bytes, data_uncompressed_bytes "+
+		"FROM system.columns WHERE database = '%s' AND table = '%s' ORDER BY position FORMAT TabSeparatedWithNames",
+		escapeValuesString(database), escapeValuesString(table))
+
+	err = conn.eachRow(query, func(result Result) (err error) {
+		var column ColumnDetail
+
+		if column.Name, err = result.String("name"); err != nil {
+			return
+		}
+
+		if column.Type, err = result.String("type"); err != nil {
+			return
+		}
+
+		if column.DefaultKind, err = result.String("default_kind"); err != nil {
+			return
+		}
+
+		if column.DefaultExpression, err = result.String("default_expression"); err != nil {
+			return
+		}
+
+		if column.Codec, err = result.String("compression_codec"); err != nil {
+			return
+		}
+
+		if column.Comment, err = result.String("comment"); err != nil {
+			return
+		}
+
+		if column.CompressedBytes, err = result.UInt64("data_compressed_bytes"); err != nil {
+			return
+		}
+
+		if column.UncompressedBytes, err = result.UInt64("data_uncompressed_bytes"); err != nil {
+			return
+		}
+
+		columns = append(columns, column)
+
+		return
+	})
+
+	return columns, err
+}
+
+// Parts returns data parts of table (both active and inactive)
+func (conn *Conn) Parts(database, table string) (parts []PartInfo, err error) {
+	query := fmt.Sprintf("SELECT name, partition, active, rows, bytes_on_disk, modification_time "+
+		"FROM system.parts WHERE database = '%s' AND table = '%s' ORDER BY name FORMAT TabSeparatedWithNames",
+		escapeValuesString(database), escapeValuesString(table))
+
+	err = conn.eachRow(query, func(result Result) (err error) {
+		var part PartInfo
+
+		if part.Name, err = result.String("name"); err != nil {
+			return
+		}
+
+		if part.Partition, err = result.String("partition"); err != nil {
+			return
+		}
+
+		if part.Active, err = result.Bool("active"); err != nil {
+			return
+		}
+
+		if part.Rows, err = result.UInt64("rows"); err != nil {
+			return
+		}
+
+		if part.BytesOnDisk, err = result.UInt64("bytes_on_disk"); err != nil {
+			return
+		}
+
+		if part.ModificationTime, err = result.DateTime("modification_time"); err != nil {
+			return
+		}
+
+		parts = append(parts, part)
+
+		return
+	})
+
+	return parts, err
+}
+
+// OptimizeTable merges data parts of `database.table` table (OPTIMIZE TABLE query)
+// Final merges all parts of every partition into one so ReplacingMergeTree rows are deduplicated
+func (conn *Conn) OptimizeTable(database, table string, final bool) error {
+	query := fmt.Sprintf("OPTIMIZE TABLE %s.%s", database, table)
+	if final {
+		query += " FINAL"
+	}
+
+	return conn.Exec(query)
+}
+
+// LastInsertInfo returns data parts created by the most recent successful insert of the connection from system.part_log
+// The server has to have part_log enabled and the log is flushed periodically so the parts may appear with delay
+// It returns no parts if the connection hasn't inserted anything yet
+func (conn *Conn) LastInsertInfo() (parts []InsertedPart, err error) {
+	conn.mux.Lock()
+	queryID := conn.lastInsertID
+	conn.mux.Unlock()
+
+	if len(queryID) == 0 {
+		cfg.logger.debug("There is no insert with known query_id")
+
+		return nil, nil
+	}
+
+	query := fmt.Sprintf("SELECT database, table, part_name, partition_id, rows, size_in_bytes "+
+		"FROM system.part_log WHERE event_type = 'NewPart' AND query_id = '%s' ORDER BY part_name FORMAT TabSeparatedWithNames",
+		escapeValuesString(queryID))
+
+	err = conn.eachRow(query, func(result Result) (err error) {
+		var part InsertedPart
+
+		if part.Database, err = result.String("database"); err != nil {
+			return
+		}
+
+		if part.Table, err = result.String("table"); err != nil {
+			return
+		}
+
+		if part.Name, err = result.String("part_name"); err != nil {
+			return
+		}
+
+		if part.PartitionID, err = result.String("partition_id"); err != nil {
+			return
+		}
+
+		if part.Rows, err = result.UInt64("rows"); err != nil {
+			return
+		}
+
+		if part.Bytes, err = result.UInt64("size_in_bytes"); err != nil {
+			return
+		}
+
+		part.MinBlock, part.MaxBlock = partBlocks(part.Name, part.PartitionID)
+
+		parts = append(parts, part)
+
+		return
+	})
+
+	return parts, err
+}
+
+// partBlocks returns block numbers of part from its name (partition ID, min block, max block, level and optional mutation)
+func partBlocks(name, partitionID string) (uint64, uint64) {
+	if !strings.HasPrefix(name, partitionID+"_") {
+		return 0, 0
+	}
+
+	fields := strings.Split(strings.TrimPrefix(name, partitionID+"_"), "_")
+	if len(fields) < 3 || len(fields) > 4 {
+		return 0, 0
+	}
+
+	minBlock, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+
+	maxBlock, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+
+	return minBlock, maxBlock
+}
+
+// eachRow calls fn for every row of query and stops on the first error
+func (conn *Conn) eachRow(query string, fn func(result Result) error) error {
+	conn.waitForRest()
+	conn.increase()
+
+	// rows of system tables mustn't be truncated by default limit of connection
+	iter, err := conn.fetchUnlimited(context.Background(), query)
+	conn.reduce()
+
+	if err != nil {
+		return err
+	}
+
+	defer iter.Close()
+
+	for iter.Next() {
+		err = fn(iter.Result)
+		if err != nil {
+			return err
+		}
+	}
+
+	return iter.Err()
+}
@@ -0,0 +1,33 @@
+package clickhouse
+
+import "testing"
+
+func TestPartBlocks(t *testing.T) {
+	tests := []struct {
+		name        string
+		part        string
+		partitionID string
+		min, max    uint64
+	}{
+		{"merged", "202301_1_5_2", "202301", 1, 5},
+		{"mutated", "202301_3_3_0_7", "202301", 3, 3},
+		{"all", "all_10_20_1", "all", 10, 20},
+		{"partition with underscore", "a_b_4_8_1", "a_b", 4, 8},
+		{"hashed partition", "e4b6e4e2d1f5c1c7a9b0c3d2e1f0a9b8_7_9_0", "e4b6e4e2d1f5c1c7a9b0c3d2e1f0a9b8", 7, 9},
+		{"other partition", "202302_1_5_2", "202301", 0, 0},
+		{"without level", "202301_1_5", "202301", 0, 0},
+		{"extra fields", "202301_1_5_2_3_4", "202301", 0, 0},
+		{"not numbers", "202301_a_b_0", "202301", 0, 0},
+		{"negative", "202301_-1_5_0", "202301", 0, 0},
+		{"empty", "", "", 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			min, max := partBlocks(test.part, test.partitionID)
+			if min != test.min || max != test.max {
+				t.Errorf("partBlocks(%q, %q) = %d, %d, want %d, %d", test.part, test.partitionID, min, max, test.min, test.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package clickhouse
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TupleScan copies elements of Tuple value into values pointed at by dest in order of the tuple elements
+// Supported destinations are the same as of Iter.Scan (nested literals can be scanned into string and parsed again)
+// Both tuple literals (TabSeparated and CSV formats) and JSON arrays (JSON formats) are supported
+func (result Result) TupleScan(column string, dest ...interface{}) error {
+	// elements of tuple literal are escaped once so the field isn't unescaped before splitting
+	value, err := result.raw(column)
+	if err != nil {
+		return err
+	}
+
+	elements, err := tupleElements(value)
+	if err != nil {
+		return tupleError(value, err)
+	}
+
+	if len(elements) != len(dest) {
+		return tupleError(value, fmt.Errorf("tuple has %d elements but %d destination arguments are passed", len(elements), len(dest)))
+	}
+
+	tuple := Result{
+		conn:     result.conn,
+		data:     make(map[string]string, len(elements)),
+		location: result.location}
+
+	for index, element := range elements {
+		tuple.data[strconv.Itoa(index)] = element
+	}
+
+	for index := range elements {
+		err = tuple.scanInto(strconv.Itoa(index), dest[index])
+		if err != nil {
+			return tupleError(value, fmt.Errorf("can't scan element %d: %s", index, err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// NamedTuple returns elements of Tuple value by names of fields of named tuple (e.g. Tuple(name String, age UInt8))
+// Names are taken from type of column (query has to be fetched with TabSeparatedWithNamesAndTypes format)
+// or from JSON object of JSON formats. Elements are keyed by positions ("0", "1" and so on) if names aren't known
+func (result Result) NamedTuple(column string) (map[string]string, error) {
+	// elements of tuple literal are escaped once so the field isn't unescaped before splitting
+	value, err := result.raw(column)
+	if err != nil {
+		return nil, err
+	}
+
+	var object map[string]json.RawMessage
+	if json.Unmarshal([]byte(value), &object) == nil {
+		elements := make(map[string]string, len(object))
+		for name, raw := range object {
+			elements[name] = jsonText(raw)
+		}
+
+		return elements, nil
+	}
+
+	elements, err := tupleElements(value)
+	if err != nil {
+		return nil, tupleError(value, err)
+	}
+
+	names := tupleNames(result.columnType(column), len(elements))
+
+	tuple := make(map[string]string, len(elements))
+	for index, element := range elements {
+		if names != nil {
+			tuple[names[index]] = element
+		} else {
+			tuple[strconv.Itoa(index)] = element
+		}
+	}
+
+	return tuple, nil
+}
+
+// columnType returns type of column if it's known
+func (result Result) columnType(column string) string {
+	if typ, ok := result.types[column]; ok || result.index == nil {
+		return typ
+	}
+
+	return result.types[result.index[strings.ToLower(column)]]
+}
+
+var fieldNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// tupleNames returns names of fields of named tuple type or nil if the type isn't named tuple of amount fields
+func tupleNames(typ string, amount int) []string {
+	typ = strings.TrimSpace(typ)
+	for _, wrapper := range []string{"LowCardinality(", "Nullable("} {
+		if strings.HasPrefix(typ, wrapper) && strings.HasSuffix(typ, ")") {
+			typ = strings.TrimSpace(typ[len(wrapper) : len(typ)-1])
+		}
+	}
+
+	if !strings.HasPrefix(typ, "Tuple(") {
+		return nil
+	}
+
+	fields, err := splitLiteral(typ[len("Tuple"):], '(', ')')
+	if err != nil || len(fields) != amount {
+		return nil
+	}
+
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		var name, rest string
+
+		if strings.HasPrefix(field, "`") {
+			end := 1
+			for end < len(field) && field[end] != '`' {
+				if field[end] == '\\' {
+					end++
+				}
+
+				end++
+			}
+
+			if end >= len(field) {
+				return nil
+			}
+
+			name, rest = unquoteIdent(field[:end+1]), field[end+1:]
+		} else {
+			name, rest, _ = strings.Cut(field, " ")
+			if !fieldNameRe.MatchString(name) {
+				return nil
+			}
+		}
+
+		// unnamed element has only type (e.g. String or Decimal(10, 2))
+		if len(strings.TrimSpace(rest)) == 0 {
+			return nil
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// tupleElements returns text of elements of tuple literal or JSON array
+func tupleElements(value string) ([]string, error) {
+	var raws []json.RawMessage
+	if json.Unmarshal([]byte(value), &raws) == nil {
+		elements := make([]string, 0, len(raws))
+		for _, raw := range raws {
+			elements = append(elements, jsonText(raw))
+		}
+
+		return elements, nil
+	}
+
+	literals, err := splitLiteral(value, '(', ')')
+	if err != nil {
+		return nil, err
+	}
+
+	elements := make([]string, 0, len(literals))
+	for _, literal := range literals {
+		elements = append(elements, literalText(literal))
+	}
+
+	return elements, nil
+}
+
+func tupleError(value string, err error) error {
+	err = fmt.Errorf("can't convert value %s to tuple: %s", value, err.Error())
+
+	cfg.logger.error(fmt.Sprintf("Catch error %s", err.Error()))
+
+	return err
+}
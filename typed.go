@@ -0,0 +1,89 @@
+package clickhouse
+
+// FetchTyped executes new query and returns all rows copied into structs with Result.ScanStruct
+// Fields are matched with columns by `ch:"column"` tag or by name
+func FetchTyped[T any](conn *Conn, query string) ([]T, error) {
+	iter, err := conn.Fetch(query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	var rows []T
+	for iter.Next() {
+		if rows == nil {
+			rows = make([]T, 0, iter.rowsCapacity())
+		}
+
+		var row T
+
+		err = iter.Result.ScanStruct(&row)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, iter.Err()
+}
+
+// TypedIter is iterator which copies rows into structs of type T one by one (see IterTyped)
+type TypedIter[T any] struct {
+	iter Iter
+	row  T
+	err  error
+}
+
+// IterTyped executes new query and returns iterator over rows copied into structs with Result.ScanStruct
+// Unlike FetchTyped it streams rows so large results aren't kept in memory
+func IterTyped[T any](conn *Conn, query string) (*TypedIter[T], error) {
+	iter, err := conn.Fetch(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TypedIter[T]{iter: iter}, nil
+}
+
+// Next copies the next row into struct returned by Row
+// It returns false if there are no more rows or on error (see Err)
+func (typed *TypedIter[T]) Next() bool {
+	if typed.err != nil || !typed.iter.Next() {
+		return false
+	}
+
+	var row T
+
+	err := typed.iter.Result.ScanStruct(&row)
+	if err != nil {
+		typed.err = err
+		typed.iter.Close()
+
+		return false
+	}
+
+	typed.row = row
+
+	return true
+}
+
+// Row returns the current row
+func (typed *TypedIter[T]) Row() T {
+	return typed.row
+}
+
+// Err returns error of fetching or copying of rows
+func (typed *TypedIter[T]) Err() error {
+	if typed.err != nil {
+		return typed.err
+	}
+
+	return typed.iter.Err()
+}
+
+// Close closes stream
+func (typed *TypedIter[T]) Close() {
+	typed.iter.Close()
+}
@@ -0,0 +1,97 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Warmup opens amount of connections to server at once with simultaneous `SELECT 1` queries
+// so the first queries don't pay for connecting and TLS handshake
+// The connections are kept for reuse so keep-alives have to be turned on (see DisableKeepAlives)
+// and the transport keeps at least amount of idle connections to the host
+// Requests limits aren't applied so amount of connections can exceed maximum amount of requests
+func (conn *Conn) Warmup(ctx context.Context, amount int) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	if atomic.LoadInt32(&conn.keepAlive) != 1 {
+		err := errors.New("can't warm up connections with disabled keep-alives")
+
+		message := fmt.Sprintf("Catch error %s", err.Error())
+		cfg.logger.error(message)
+
+		return err
+	}
+
+	// the transport is shared with clones so it's replaced with changed copy instead of changing it in place
+	if conn.transport.get().MaxIdleConnsPerHost < amount {
+		conn.transport.update(func(transport *http.Transport) {
+			if transport.MaxIdleConnsPerHost < amount {
+				transport.MaxIdleConnsPerHost = amount
+			}
+		})
+	}
+
+	message := fmt.Sprintf("Try to warm up %d connections", amount)
+	cfg.logger.debug(message)
+
+	var (
+		started sync.WaitGroup
+		done    sync.WaitGroup
+		once    sync.Once
+		first   error
+	)
+
+	// connections are returned for reuse after reading of responses so all of them are held till every query is answered
+	ready := make(chan struct{})
+
+	started.Add(amount)
+	done.Add(amount)
+
+	for i := 0; i < amount; i++ {
+		go func() {
+			defer done.Done()
+
+			reader, _, err := conn.doQuery(ctx, "SELECT 1", nil)
+
+			started.Done()
+
+			if err != nil {
+				once.Do(func() { first = err })
+
+				return
+			}
+
+			<-ready
+
+			_, err = io.Copy(io.Discard, reader)
+			reader.Close()
+
+			if err != nil {
+				once.Do(func() { first = err })
+			}
+		}()
+	}
+
+	started.Wait()
+	close(ready)
+	done.Wait()
+
+	if first != nil {
+		message = fmt.Sprintf("Catch error can't warm up connections: %s", first.Error())
+		cfg.logger.error(message)
+
+		return fmt.Errorf("can't warm up connections: %w", first)
+	}
+
+	message = fmt.Sprintf("Warmed up %d connections", amount)
+	cfg.logger.debug(message)
+
+	return nil
+}